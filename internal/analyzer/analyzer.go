@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"cmp"
 	"fmt"
 	"log/slog"
 	"os"
@@ -15,11 +16,13 @@ import (
 type Analyzer struct {
 	changedFiles  map[string]struct{} // Set of changed file absolute paths
 	analysisCache map[string]bool     // Cache of analysis results, key: absolute module path, value: isUpdated
+	chainCache    map[string][]Chain  // Cache of change chains, key: absolute module path
+	basePath      string              // Version files above it do not apply to the modules
 	logger        *slog.Logger
 }
 
-// NewAnalyzer creates a new Analyzer instance
-func NewAnalyzer(changedFiles map[string]struct{}, logger *slog.Logger) (*Analyzer, error) {
+// NewAnalyzer creates a new Analyzer instance. Version files in parent directories apply to the modules up to basePath.
+func NewAnalyzer(changedFiles map[string]struct{}, basePath string, logger *slog.Logger) (*Analyzer, error) {
 	absChangedFiles := make(map[string]struct{})
 	for path := range changedFiles {
 		absPath, err := filepath.Abs(path)
@@ -32,6 +35,8 @@ func NewAnalyzer(changedFiles map[string]struct{}, logger *slog.Logger) (*Analyz
 	return &Analyzer{
 		changedFiles:  absChangedFiles,
 		analysisCache: make(map[string]bool),
+		chainCache:    make(map[string][]Chain),
+		basePath:      basePath,
 		logger:        logger,
 	}, nil
 }
@@ -149,14 +154,24 @@ func (a *Analyzer) IsModuleUpdated(moduleDir string) (bool, error) {
 
 // hasDirectFileChanges checks if any files in the module directory have changed
 func (a *Analyzer) hasDirectFileChanges(moduleDir string) (bool, error) {
+	changedFiles, err := a.directChangedFiles(moduleDir)
+	if err != nil {
+		return false, err
+	}
+	return len(changedFiles) > 0, nil
+}
+
+// directChangedFiles returns the absolute paths of the changed files in the module directory
+func (a *Analyzer) directChangedFiles(moduleDir string) ([]string, error) {
 	moduleDir = filepath.Clean(moduleDir)
 
 	// Check files in the root of the module directory (non-recursive)
 	entries, err := os.ReadDir(moduleDir)
 	if err != nil {
-		return false, fmt.Errorf("failed to read directory %s: %w", moduleDir, err)
+		return nil, fmt.Errorf("failed to read directory %s: %w", moduleDir, err)
 	}
 
+	changedFiles := make([]string, 0)
 	// Check files in the module directory itself
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -169,16 +184,209 @@ func (a *Analyzer) hasDirectFileChanges(moduleDir string) (bool, error) {
 		// Convert to absolute path for comparison
 		absFilePath, err := filepath.Abs(filePath)
 		if err != nil {
-			return false, fmt.Errorf("failed to get absolute path for %s: %w", filePath, err)
+			return nil, fmt.Errorf("failed to get absolute path for %s: %w", filePath, err)
 		}
 
 		if _, found := a.changedFiles[absFilePath]; found {
 			a.logger.Debug("Found changed file in module root", "file", absFilePath, "module", moduleDir)
-			return true, nil
+			changedFiles = append(changedFiles, absFilePath)
 		}
 	}
 
-	return false, nil
+	return changedFiles, nil
+}
+
+// hasInheritedVersionFileChanges checks if a changed version file in a parent directory
+// applies to the module, i.e. it is not overridden for every tool it can pin by a version file
+// of the same name closer to the module
+func (a *Analyzer) hasInheritedVersionFileChanges(moduleDir string) (bool, error) {
+	changedFiles, err := a.inheritedVersionFileChanges(moduleDir)
+	if err != nil {
+		return false, err
+	}
+	return len(changedFiles) > 0, nil
+}
+
+// inheritedVersionFileChanges returns the absolute paths of the changed version files
+// in parent directories up to the base path that apply to the module.
+// Modules whose directory does not exist have none.
+func (a *Analyzer) inheritedVersionFileChanges(moduleDir string) ([]string, error) {
+	absModuleDir, err := filepath.Abs(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", moduleDir, err)
+	}
+
+	changedFiles := make([]string, 0)
+	if info, err := os.Stat(absModuleDir); err != nil || !info.IsDir() {
+		a.logger.Debug("Skipping version files of missing module directory", "module", moduleDir)
+		return changedFiles, nil
+	}
+
+	// The same directories terraform.ResolveVersion searches
+	dirs, err := terraform.VersionFileDirs(absModuleDir, a.basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for changedFile := range a.changedFiles {
+		if !terraform.IsVersionFile(changedFile) {
+			continue
+		}
+		// Changes in the module directory itself are direct changes
+		index := slices.Index(dirs, filepath.Dir(changedFile))
+		if index <= 0 {
+			continue
+		}
+
+		// The previous content is unknown, so the changed file may have pinned any tool it can pin.
+		// Each version manager reads its own file, so only a file of the same name closer to the module
+		// pinning the tool overrides it.
+		for _, tool := range terraform.VersionFileTools(changedFile) {
+			overridingVersion, err := terraform.FindNearestVersionFile(dirs[:index], filepath.Base(changedFile), tool)
+			if err != nil {
+				return nil, err
+			}
+			if overridingVersion == nil {
+				a.logger.Debug("Found changed version file in parent directory", "file", changedFile, "module", moduleDir, "tool", tool)
+				changedFiles = append(changedFiles, changedFile)
+				break
+			}
+		}
+	}
+
+	slices.Sort(changedFiles)
+	return changedFiles, nil
+}
+
+// Changes describes the changes that affect a module
+type Changes struct {
+	ChangedFiles      []string // Absolute paths of the changed files affecting the module, sorted
+	TriggeringModules []string // Absolute paths of the child modules containing changed files, sorted
+	Chains            []Chain  // Every path from the module to a changed file, sorted by file and modules
+}
+
+// Chain is a path through the module tree from a module to a changed file affecting it
+type Chain struct {
+	Modules []string // Absolute paths of the modules from the analyzed module to the one containing the file
+	File    string   // Absolute path of the changed file
+}
+
+// CollectChanges returns the changed files and child modules that affect the module.
+// Unlike IsModuleUpdated it walks every module the module depends on, so all changes are reported.
+func (a *Analyzer) CollectChanges(moduleDir string) (*Changes, error) {
+	absModuleDir, err := filepath.Abs(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", moduleDir, err)
+	}
+
+	closure, err := FindModuleClosure(absModuleDir, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &Changes{
+		ChangedFiles:      make([]string, 0),
+		TriggeringModules: make([]string, 0),
+	}
+	for _, dir := range closure {
+		changedFiles, err := a.directChangedFiles(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check direct changes in %s: %w", dir, err)
+		}
+		if len(changedFiles) > 0 {
+			changes.ChangedFiles = append(changes.ChangedFiles, changedFiles...)
+			if dir != absModuleDir {
+				changes.TriggeringModules = append(changes.TriggeringModules, dir)
+			}
+		}
+	}
+
+	// Version files in parent directories govern the module as well
+	versionFiles, err := a.inheritedVersionFileChanges(absModuleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check version files of %s: %w", absModuleDir, err)
+	}
+	changes.ChangedFiles = append(changes.ChangedFiles, versionFiles...)
+
+	chains, _, err := a.changeChains(absModuleDir, make(map[string]struct{}))
+	if err != nil {
+		return nil, err
+	}
+	changes.Chains = slices.Clone(chains)
+	for _, versionFile := range versionFiles {
+		changes.Chains = append(changes.Chains, Chain{Modules: []string{absModuleDir}, File: versionFile})
+	}
+
+	slices.Sort(changes.ChangedFiles)
+	changes.ChangedFiles = slices.Compact(changes.ChangedFiles)
+	slices.Sort(changes.TriggeringModules)
+	slices.SortFunc(changes.Chains, func(a, b Chain) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), slices.Compare(a.Modules, b.Modules))
+	})
+	return changes, nil
+}
+
+// changeChains returns every path from the module to a changed file in the module or in a module
+// it depends on. Unlike IsModuleUpdated it does not stop at the first change, and results are cached
+// per module so that shared child modules are only walked once. Modules in visiting are part of the
+// current path and are skipped to break cycles. The returned flag reports whether a cycle was cut
+// below the module; such results depend on the current path and are not cached.
+func (a *Analyzer) changeChains(moduleDir string, visiting map[string]struct{}) ([]Chain, bool, error) {
+	if chains, found := a.chainCache[moduleDir]; found {
+		return chains, false, nil
+	}
+	if _, found := visiting[moduleDir]; found {
+		return nil, true, nil
+	}
+	visiting[moduleDir] = struct{}{}
+	defer delete(visiting, moduleDir)
+
+	// Missing and unparsable modules are reported by FindModuleClosure
+	if _, err := os.Stat(moduleDir); os.IsNotExist(err) {
+		return nil, false, nil
+	}
+
+	chains := make([]Chain, 0)
+	cut := false
+	changedFiles, err := a.directChangedFiles(moduleDir)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check direct changes in %s: %w", moduleDir, err)
+	}
+	for _, changedFile := range changedFiles {
+		chains = append(chains, Chain{Modules: []string{moduleDir}, File: changedFile})
+	}
+
+	childModules, err := terraform.FindChildModules(moduleDir)
+	if err == nil {
+		children := make([]string, 0, len(childModules))
+		for _, childModule := range childModules {
+			absChildModule, err := filepath.Abs(childModule)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to get absolute path for %s: %w", childModule, err)
+			}
+			children = append(children, absChildModule)
+		}
+		slices.Sort(children)
+
+		for _, child := range slices.Compact(children) {
+			childChains, childCut, err := a.changeChains(child, visiting)
+			if err != nil {
+				return nil, false, err
+			}
+			cut = cut || childCut
+			for _, childChain := range childChains {
+				chains = append(chains, Chain{
+					Modules: append([]string{moduleDir}, childChain.Modules...),
+					File:    childChain.File,
+				})
+			}
+		}
+	}
+
+	if !cut {
+		a.chainCache[moduleDir] = chains
+	}
+	return chains, cut, nil
 }
 
 // GetAnalysisCache returns the analysis cache (useful for testing)
@@ -189,6 +397,47 @@ func (a *Analyzer) GetAnalysisCache() map[string]bool {
 // ClearCache clears the analysis cache
 func (a *Analyzer) ClearCache() {
 	a.analysisCache = make(map[string]bool)
+	a.chainCache = make(map[string][]Chain)
+}
+
+// FindModuleClosure returns the absolute paths of the module and every local module it depends on,
+// directly or through other modules. The module comes first and the other modules are sorted.
+// Modules that do not exist or cannot be parsed are logged and skipped.
+func FindModuleClosure(moduleDir string, logger *slog.Logger) ([]string, error) {
+	absModuleDir, err := filepath.Abs(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", moduleDir, err)
+	}
+
+	closure := make([]string, 0)
+	visited := make(map[string]struct{})
+	queue := []string{absModuleDir}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if _, seen := visited[current]; seen {
+			continue
+		}
+		visited[current] = struct{}{}
+
+		if _, err := os.Stat(current); os.IsNotExist(err) {
+			logger.Warn("Module directory does not exist", "module", current)
+			continue
+		}
+		closure = append(closure, current)
+
+		childModules, err := terraform.FindChildModules(current)
+		if err != nil {
+			logger.Warn("Failed to find child modules", "module", current, "error", err)
+			continue
+		}
+		queue = append(queue, childModules...)
+	}
+
+	if len(closure) > 1 {
+		slices.Sort(closure[1:])
+	}
+	return closure, nil
 }
 
 // ConvertToRelativePath converts an absolute path to a relative path from basePath
@@ -221,11 +470,29 @@ func ConvertToRelativePath(basePath, absolutePath string) (string, error) {
 
 // AnalyzeRootModules analyzes multiple root modules and returns the list of updated ones
 func AnalyzeRootModules(rootModuleDirs []string, changedFiles map[string]struct{}, basePath string, logger *slog.Logger) ([]string, error) {
-	analyzer, err := NewAnalyzer(changedFiles, logger)
+	updatedModuleDirs, err := FindUpdatedRootModules(rootModuleDirs, changedFiles, basePath, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedModules, err := ConvertToRelativePaths(basePath, updatedModuleDirs, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(updatedModules)
+
+	return updatedModules, nil
+}
+
+// FindUpdatedRootModules analyzes multiple root modules and returns the absolute paths of the updated ones.
+// Version files in parent directories apply to the root modules up to basePath.
+func FindUpdatedRootModules(rootModuleDirs []string, changedFiles map[string]struct{}, basePath string, logger *slog.Logger) ([]string, error) {
+	analyzer, err := NewAnalyzer(changedFiles, basePath, logger)
 	if err != nil {
 		return nil, err
 	}
-	updatedModules := make([]string, 0)
+	updatedModuleDirs := make([]string, 0)
 
 	for _, moduleDir := range rootModuleDirs {
 		updated, err := analyzer.IsModuleUpdated(moduleDir)
@@ -233,28 +500,49 @@ func AnalyzeRootModules(rootModuleDirs []string, changedFiles map[string]struct{
 			return nil, fmt.Errorf("failed to analyze module %s: %w", moduleDir, err)
 		}
 
-		if updated {
-			absoluteBasePath, err := filepath.Abs(basePath)
+		if !updated {
+			// Version files in parent directories govern the root module as well
+			updated, err = analyzer.hasInheritedVersionFileChanges(moduleDir)
 			if err != nil {
-				logger.Error("Failed to get absolute path for base path", "path", basePath, "error", err)
-				return nil, err
+				return nil, fmt.Errorf("failed to check version files of %s: %w", moduleDir, err)
 			}
-			// Convert to relative path for output
+		}
+
+		if updated {
 			absoluteModuleDir, err := filepath.Abs(moduleDir)
 			if err != nil {
 				logger.Error("Failed to get absolute path for module directory", "path", moduleDir, "error", err)
 				return nil, err
 			}
-			relPath, err := ConvertToRelativePath(absoluteBasePath, absoluteModuleDir)
-			if err != nil {
-				logger.Error("Failed to convert to relative path, using original", "path", moduleDir, "error", err)
-				return nil, err
-			}
-			updatedModules = append(updatedModules, relPath)
+			updatedModuleDirs = append(updatedModuleDirs, absoluteModuleDir)
 		}
 	}
 
-	slices.Sort(updatedModules)
+	return updatedModuleDirs, nil
+}
 
-	return updatedModules, nil
+// ConvertToRelativePaths converts module directories to paths relative to basePath for output
+func ConvertToRelativePaths(basePath string, moduleDirs []string, logger *slog.Logger) ([]string, error) {
+	absoluteBasePath, err := filepath.Abs(basePath)
+	if err != nil {
+		logger.Error("Failed to get absolute path for base path", "path", basePath, "error", err)
+		return nil, err
+	}
+
+	relPaths := make([]string, 0, len(moduleDirs))
+	for _, moduleDir := range moduleDirs {
+		absoluteModuleDir, err := filepath.Abs(moduleDir)
+		if err != nil {
+			logger.Error("Failed to get absolute path for module directory", "path", moduleDir, "error", err)
+			return nil, err
+		}
+		relPath, err := ConvertToRelativePath(absoluteBasePath, absoluteModuleDir)
+		if err != nil {
+			logger.Error("Failed to convert to relative path, using original", "path", moduleDir, "error", err)
+			return nil, err
+		}
+		relPaths = append(relPaths, relPath)
+	}
+
+	return relPaths, nil
 }
@@ -4,7 +4,11 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
 )
 
 func getTestLogger() *slog.Logger {
@@ -17,7 +21,7 @@ func TestIsModuleUpdated_NoChanges(t *testing.T) {
 	// Setup: No changed files
 	changedFiles := make(map[string]struct{})
 	logger := getTestLogger()
-	analyzer, err := NewAnalyzer(changedFiles, logger)
+	analyzer, err := NewAnalyzer(changedFiles, filepath.Join("..", "..", "mock-terraform"), logger)
 	if err != nil {
 		t.Fatalf("Failed to create analyzer: %v", err)
 	}
@@ -44,7 +48,7 @@ func TestIsModuleUpdated_DirectChange(t *testing.T) {
 		filepath.Join(moduleDir, "main.tf"): struct{}{},
 	}
 	logger := getTestLogger()
-	analyzer, err := NewAnalyzer(changedFiles, logger)
+	analyzer, err := NewAnalyzer(changedFiles, mockTerraformDir, logger)
 	if err != nil {
 		t.Fatalf("Failed to create analyzer: %v", err)
 	}
@@ -73,7 +77,7 @@ func TestIsModuleUpdated_ChildModuleChange(t *testing.T) {
 		childModulePath: struct{}{},
 	}
 	logger := getTestLogger()
-	analyzer, err := NewAnalyzer(changedFiles, logger)
+	analyzer, err := NewAnalyzer(changedFiles, mockTerraformDir, logger)
 	if err != nil {
 		t.Fatalf("Failed to create analyzer: %v", err)
 	}
@@ -100,7 +104,7 @@ func TestIsModuleUpdated_GrandchildModuleChange(t *testing.T) {
 		filepath.Join(mockTerraformDir, "modules", "service", "service-1", "main.tf"): struct{}{},
 	}
 	logger := getTestLogger()
-	analyzer, err := NewAnalyzer(changedFiles, logger)
+	analyzer, err := NewAnalyzer(changedFiles, mockTerraformDir, logger)
 	if err != nil {
 		t.Fatalf("Failed to create analyzer: %v", err)
 	}
@@ -123,7 +127,7 @@ func TestIsModuleUpdated_Cache(t *testing.T) {
 		filepath.Join(moduleDir, "main.tf"): struct{}{},
 	}
 	logger := getTestLogger()
-	analyzer, err := NewAnalyzer(changedFiles, logger)
+	analyzer, err := NewAnalyzer(changedFiles, mockTerraformDir, logger)
 	if err != nil {
 		t.Fatalf("Failed to create analyzer: %v", err)
 	}
@@ -157,7 +161,7 @@ func TestIsModuleUpdated_Cache(t *testing.T) {
 func TestIsModuleUpdated_NonExistentModule(t *testing.T) {
 	changedFiles := make(map[string]struct{})
 	logger := getTestLogger()
-	analyzer, err := NewAnalyzer(changedFiles, logger)
+	analyzer, err := NewAnalyzer(changedFiles, ".", logger)
 	if err != nil {
 		t.Fatalf("Failed to create analyzer: %v", err)
 	}
@@ -321,7 +325,7 @@ func TestHasDirectFileChanges(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			logger := getTestLogger()
-			analyzer, err := NewAnalyzer(tt.changedFiles, logger)
+			analyzer, err := NewAnalyzer(tt.changedFiles, mockTerraformDir, logger)
 			if err != nil {
 				t.Fatalf("Failed to create analyzer: %v", err)
 			}
@@ -341,7 +345,7 @@ func TestHasDirectFileChanges(t *testing.T) {
 func TestClearCache(t *testing.T) {
 	changedFiles := make(map[string]struct{})
 	logger := getTestLogger()
-	analyzer, err := NewAnalyzer(changedFiles, logger)
+	analyzer, err := NewAnalyzer(changedFiles, ".", logger)
 	if err != nil {
 		t.Fatalf("Failed to create analyzer: %v", err)
 	}
@@ -363,7 +367,7 @@ func TestClearCache(t *testing.T) {
 func TestGetAnalysisCache(t *testing.T) {
 	changedFiles := make(map[string]struct{})
 	logger := getTestLogger()
-	analyzer, err := NewAnalyzer(changedFiles, logger)
+	analyzer, err := NewAnalyzer(changedFiles, ".", logger)
 	if err != nil {
 		t.Fatalf("Failed to create analyzer: %v", err)
 	}
@@ -380,3 +384,203 @@ func TestGetAnalysisCache(t *testing.T) {
 		t.Error("Expected 'test' to be in cache")
 	}
 }
+
+func TestFindUpdatedRootModules_InheritedVersionFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		basePath     string // Relative to the temporary directory, which is used if empty
+		rootModules  []string
+		changedFiles []string
+		expected     []string
+	}{
+		{
+			name: "Terraform version file",
+			files: map[string]string{
+				"live/.terraform-version":          "1.5.7",
+				"live/dev/main.tf":                 `resource "null_resource" "this" {}`,
+				"live/dev/modules/network/main.tf": `resource "null_resource" "this" {}`,
+				"live/prod/main.tf":                `resource "null_resource" "this" {}`,
+				"live/prod/.terraform-version":     "1.6.0",
+				"live/staging/main.tf":             `resource "null_resource" "this" {}`,
+				"live/staging/.tool-versions":      "terraform 1.6.0",
+				"live/qa/main.tf":                  `resource "null_resource" "this" {}`,
+				"live/qa/.tool-versions":           "nodejs 20.0.0",
+				"other/main.tf":                    `resource "null_resource" "this" {}`,
+			},
+			rootModules:  []string{"live/dev", "live/prod", "live/staging", "live/qa", "live/missing", "other"},
+			changedFiles: []string{"live/.terraform-version"},
+			// Only prod pins its own .terraform-version; .tool-versions is read by another version manager
+			expected: []string{"live/dev", "live/staging", "live/qa"},
+		},
+		{
+			name: "Tool versions file overridden per tool",
+			files: map[string]string{
+				"live/.tool-versions":         "nodejs 20.0.0\nterraform 1.5.7",
+				"live/dev/main.tf":            `resource "null_resource" "this" {}`,
+				"live/prod/main.tf":           `resource "null_resource" "this" {}`,
+				"live/prod/.tool-versions":    "terraform 1.6.0\nopentofu 1.7.0",
+				"live/canary/main.tf":         `resource "null_resource" "this" {}`,
+				"live/canary/.tool-versions":  "opentofu 1.7.0",
+				"live/staging/main.tf":        `resource "null_resource" "this" {}`,
+				"live/staging/.tool-versions": "nodejs 22.0.0",
+				"live/qa/main.tf":             `resource "null_resource" "this" {}`,
+				"live/qa/.terraform-version":  "1.6.0",
+			},
+			rootModules:  []string{"live/dev", "live/prod", "live/canary", "live/staging", "live/qa"},
+			changedFiles: []string{"live/.tool-versions"},
+			// Only prod pins both tools the changed file may have pinned before
+			expected: []string{"live/dev", "live/canary", "live/staging", "live/qa"},
+		},
+		{
+			name: "Tool versions file no longer pinning terraform",
+			files: map[string]string{
+				"live/.tool-versions": "nodejs 20.0.0",
+				"live/dev/main.tf":    `resource "null_resource" "this" {}`,
+			},
+			rootModules:  []string{"live/dev"},
+			changedFiles: []string{"live/.tool-versions"},
+			expected:     []string{"live/dev"},
+		},
+		{
+			name: "Version file above base path",
+			files: map[string]string{
+				"live/.terraform-version": "1.5.7",
+				"live/dev/main.tf":        `resource "null_resource" "this" {}`,
+			},
+			basePath:     "live/dev",
+			rootModules:  []string{"live/dev"},
+			changedFiles: []string{"live/.terraform-version"},
+			expected:     []string{},
+		},
+		{
+			name: "Deleted tool versions file",
+			files: map[string]string{
+				"live/dev/main.tf": `resource "null_resource" "this" {}`,
+			},
+			rootModules:  []string{"live/dev"},
+			changedFiles: []string{"live/.tool-versions"},
+			expected:     []string{"live/dev"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			testutil.WriteFiles(t, dir, tt.files)
+
+			rootModuleDirs := make([]string, 0, len(tt.rootModules))
+			for _, rootModule := range tt.rootModules {
+				rootModuleDirs = append(rootModuleDirs, filepath.Join(dir, rootModule))
+			}
+			changedFiles := make(map[string]struct{}, len(tt.changedFiles))
+			for _, changedFile := range tt.changedFiles {
+				changedFiles[filepath.Join(dir, changedFile)] = struct{}{}
+			}
+
+			updatedModules, err := FindUpdatedRootModules(rootModuleDirs, changedFiles, filepath.Join(dir, tt.basePath), getTestLogger())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected := make([]string, 0, len(tt.expected))
+			for _, rootModule := range tt.expected {
+				expected = append(expected, filepath.Join(dir, rootModule))
+			}
+			if !slices.Equal(updatedModules, expected) {
+				t.Errorf("Expected updated modules %v, got %v", expected, updatedModules)
+			}
+		})
+	}
+}
+
+func TestCollectChanges(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".terraform-version":     "1.6.0",
+		"live/prod/main.tf":      "module \"a\" {\n  source = \"../../modules/a\"\n}\nmodule \"c\" {\n  source = \"../../modules/c\"\n}\n",
+		"live/prod/variables.tf": `variable "name" {}`,
+		"modules/a/main.tf":      "module \"b\" {\n  source = \"../b\"\n}\n",
+		"modules/b/main.tf":      `resource "null_resource" "this" {}`,
+		"modules/c/main.tf":      "module \"b\" {\n  source = \"../b\"\n}\n",
+		"modules/d/main.tf":      `resource "null_resource" "this" {}`,
+	}
+	testutil.WriteFiles(t, dir, files)
+
+	changedFiles := map[string]struct{}{
+		filepath.Join(dir, ".terraform-version"):      {},
+		filepath.Join(dir, "live", "prod", "main.tf"): {},
+		filepath.Join(dir, "modules", "b", "main.tf"): {},
+		filepath.Join(dir, "modules", "d", "main.tf"): {},
+	}
+	analyzer, err := NewAnalyzer(changedFiles, dir, getTestLogger())
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+
+	changes, err := analyzer.CollectChanges(filepath.Join(dir, "live", "prod"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedFiles := []string{
+		filepath.Join(dir, ".terraform-version"),
+		filepath.Join(dir, "live", "prod", "main.tf"),
+		filepath.Join(dir, "modules", "b", "main.tf"),
+	}
+	if !slices.Equal(changes.ChangedFiles, expectedFiles) {
+		t.Errorf("Expected changed files %v, got %v", expectedFiles, changes.ChangedFiles)
+	}
+	expectedModules := []string{filepath.Join(dir, "modules", "b")}
+	if !slices.Equal(changes.TriggeringModules, expectedModules) {
+		t.Errorf("Expected triggering modules %v, got %v", expectedModules, changes.TriggeringModules)
+	}
+
+	// Every path to the shared module is reported, not just the first one found
+	prod := filepath.Join(dir, "live", "prod")
+	expectedChains := []Chain{
+		{Modules: []string{prod}, File: filepath.Join(dir, ".terraform-version")},
+		{Modules: []string{prod}, File: filepath.Join(prod, "main.tf")},
+		{Modules: []string{prod, filepath.Join(dir, "modules", "a"), filepath.Join(dir, "modules", "b")}, File: filepath.Join(dir, "modules", "b", "main.tf")},
+		{Modules: []string{prod, filepath.Join(dir, "modules", "c"), filepath.Join(dir, "modules", "b")}, File: filepath.Join(dir, "modules", "b", "main.tf")},
+	}
+	if !reflect.DeepEqual(changes.Chains, expectedChains) {
+		t.Errorf("Expected chains %v, got %v", expectedChains, changes.Chains)
+	}
+}
+
+func TestCollectChanges_Cycle(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"live/a/main.tf":    "module \"x\" {\n  source = \"../../modules/x\"\n}\n",
+		"live/b/main.tf":    "module \"y\" {\n  source = \"../../modules/y\"\n}\n",
+		"modules/x/main.tf": "module \"y\" {\n  source = \"../y\"\n}\n",
+		"modules/y/main.tf": "module \"x\" {\n  source = \"../x\"\n}\n",
+	}
+	testutil.WriteFiles(t, dir, files)
+
+	changedFile := filepath.Join(dir, "modules", "x", "main.tf")
+	analyzer, err := NewAnalyzer(map[string]struct{}{changedFile: {}}, dir, getTestLogger())
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+
+	// Walking from a cuts the cycle at x below y, which must not hide the change from b
+	x := filepath.Join(dir, "modules", "x")
+	y := filepath.Join(dir, "modules", "y")
+	for _, tt := range []struct {
+		root     string
+		expected []Chain
+	}{
+		{root: "a", expected: []Chain{{Modules: []string{filepath.Join(dir, "live", "a"), x}, File: changedFile}}},
+		{root: "b", expected: []Chain{{Modules: []string{filepath.Join(dir, "live", "b"), y, x}, File: changedFile}}},
+	} {
+		changes, err := analyzer.CollectChanges(filepath.Join(dir, "live", tt.root))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(changes.Chains, tt.expected) {
+			t.Errorf("Expected chains of %s %v, got %v", tt.root, tt.expected, changes.Chains)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package atlantis
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/analyzer"
+	"github.com/hurack3034217/tf-mod-watcher/internal/terraform"
+)
+
+// header marks the file as generated so that it is not edited by hand
+const header = "# Code generated by tf-mod-watcher generate atlantis. DO NOT EDIT.\n"
+
+// Config is a repo-level atlantis.yaml
+type Config struct {
+	Version  int       `yaml:"version"`
+	Projects []Project `yaml:"projects"`
+}
+
+// Project is an Atlantis project for a root module
+type Project struct {
+	Name      string   `yaml:"name"`
+	Dir       string   `yaml:"dir"`
+	Autoplan  Autoplan `yaml:"autoplan"`
+	DependsOn []string `yaml:"depends_on,omitempty"`
+}
+
+// Autoplan configures when Atlantis plans a project automatically
+type Autoplan struct {
+	Enabled      bool     `yaml:"enabled"`
+	WhenModified []string `yaml:"when_modified"`
+}
+
+// NewConfig creates a Config with the given projects
+func NewConfig(projects []Project) Config {
+	return Config{Version: 3, Projects: projects}
+}
+
+// Marshal renders the configuration as atlantis.yaml
+func Marshal(config Config) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(header)
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, fmt.Errorf("failed to encode atlantis.yaml: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode atlantis.yaml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WhenModified returns the patterns, relative to the root module, matching every file whose change
+// affects the root module: files of the root module and of every local module in its closure,
+// files read by those modules, and version files in parent directories up to basePath
+func WhenModified(rootModuleDir, basePath string, logger *slog.Logger) ([]string, error) {
+	absRootModuleDir, err := filepath.Abs(rootModuleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", rootModuleDir, err)
+	}
+	absBasePath, err := filepath.Abs(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", basePath, err)
+	}
+
+	closure, err := analyzer.FindModuleClosure(absRootModuleDir, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]string, 0)
+	addPattern := func(target string) error {
+		relPath, err := filepath.Rel(absRootModuleDir, target)
+		if err != nil {
+			return fmt.Errorf("failed to compute relative path of %s: %w", target, err)
+		}
+		pattern := filepath.ToSlash(relPath)
+		if !slices.Contains(patterns, pattern) {
+			patterns = append(patterns, pattern)
+		}
+		return nil
+	}
+
+	// Any file directly in a module directory is part of the module
+	for _, dir := range closure {
+		if err := addPattern(filepath.Join(dir, "*")); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dir := range closure {
+		files, err := terraform.FindReferencedFiles(dir)
+		if err != nil {
+			logger.Debug("Failed to find referenced files", "module", dir, "error", err)
+			continue
+		}
+		for _, file := range files {
+			if slices.Contains(closure, filepath.Dir(file)) {
+				continue
+			}
+			if err := addPattern(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Version files in parent directories pin the CLI version of the root module
+	for dir := filepath.Dir(absRootModuleDir); isWithin(absBasePath, dir); dir = filepath.Dir(dir) {
+		for _, name := range terraform.VersionFileNames() {
+			if err := addPattern(filepath.Join(dir, name)); err != nil {
+				return nil, err
+			}
+		}
+		if dir == absBasePath || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	return patterns, nil
+}
+
+// isWithin reports whether dir is the base directory or one of its subdirectories
+func isWithin(base, dir string) bool {
+	relPath, err := filepath.Rel(base, dir)
+	if err != nil {
+		return false
+	}
+	return relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
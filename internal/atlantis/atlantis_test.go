@@ -0,0 +1,96 @@
+package atlantis
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestWhenModified(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "service" {
+  source = "../../modules/service"
+}`,
+		"modules/service/main.tf": `module "network" {
+  source = "../network"
+}
+resource "aws_instance" "this" {
+  user_data = templatefile("${path.module}/../../templates/user_data.sh", {})
+}`,
+		"modules/service/policy.tf": `locals {
+  policy = file("${path.module}/policy.json")
+}`,
+		"modules/network/main.tf": `resource "aws_vpc" "this" {}`,
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	patterns, err := WhenModified(filepath.Join(dir, "envs", "prod"), dir, logger)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"*",
+		"../../modules/network/*",
+		"../../modules/service/*",
+		"../../templates/user_data.sh",
+		"../.terraform-version",
+		"../.opentofu-version",
+		"../.tool-versions",
+		"../../.terraform-version",
+		"../../.opentofu-version",
+		"../../.tool-versions",
+	}
+	if !reflect.DeepEqual(patterns, expected) {
+		t.Errorf("Expected patterns %v, got %v", expected, patterns)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	config := NewConfig([]Project{
+		{
+			Name:     "envs/network",
+			Dir:      "envs/network",
+			Autoplan: Autoplan{Enabled: true, WhenModified: []string{"*"}},
+		},
+		{
+			Name:      "envs/app",
+			Dir:       "envs/app",
+			Autoplan:  Autoplan{Enabled: true, WhenModified: []string{"*", "../../modules/app/*"}},
+			DependsOn: []string{"envs/network"},
+		},
+	})
+
+	document, err := Marshal(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `# Code generated by tf-mod-watcher generate atlantis. DO NOT EDIT.
+version: 3
+projects:
+  - name: envs/network
+    dir: envs/network
+    autoplan:
+      enabled: true
+      when_modified:
+        - '*'
+  - name: envs/app
+    dir: envs/app
+    autoplan:
+      enabled: true
+      when_modified:
+        - '*'
+        - ../../modules/app/*
+    depends_on:
+      - envs/network
+`
+	if string(document) != expected {
+		t.Errorf("Expected document:\n%s\ngot:\n%s", expected, document)
+	}
+}
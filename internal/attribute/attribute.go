@@ -0,0 +1,125 @@
+package attribute
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// placeholderPattern matches {name} placeholders in glob-style patterns
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Pattern derives attributes of a root module from its path relative to the base path.
+// Patterns starting with ^ are regular expressions whose named groups become attributes.
+// Other patterns are glob-style, e.g. "environments/{org}/{service}/{env}", where each {name}
+// captures one path element and * matches one path element without capturing it.
+type Pattern struct {
+	pattern string
+	matcher *regexp.Regexp
+}
+
+// Filter restricts root modules to those with the given attribute value
+type Filter struct {
+	Key   string
+	Value string
+}
+
+// ParsePattern parses a path pattern, which must capture at least one attribute
+func ParsePattern(pattern string) (Pattern, error) {
+	expr := pattern
+	if !strings.HasPrefix(pattern, "^") {
+		expr = "^" + globToRegexp(filepath.ToSlash(pattern)) + "$"
+	}
+
+	matcher, err := regexp.Compile(expr)
+	if err != nil {
+		return Pattern{}, fmt.Errorf("invalid path pattern %s: %w", pattern, err)
+	}
+	if !slices.ContainsFunc(matcher.SubexpNames(), func(name string) bool { return name != "" }) {
+		return Pattern{}, fmt.Errorf("invalid path pattern %s: must capture at least one named attribute", pattern)
+	}
+
+	return Pattern{pattern: pattern, matcher: matcher}, nil
+}
+
+// globToRegexp converts a glob-style pattern to a regular expression
+func globToRegexp(pattern string) string {
+	names := placeholderPattern.FindAllStringSubmatch(pattern, -1)
+
+	var expr strings.Builder
+	for i, literal := range placeholderPattern.Split(pattern, -1) {
+		for j, part := range strings.Split(literal, "*") {
+			if j > 0 {
+				expr.WriteString("[^/]+")
+			}
+			expr.WriteString(regexp.QuoteMeta(part))
+		}
+		if i < len(names) {
+			fmt.Fprintf(&expr, "(?P<%s>[^/]+)", names[i][1])
+		}
+	}
+	return expr.String()
+}
+
+// String returns the pattern as given
+func (p Pattern) String() string {
+	return p.pattern
+}
+
+// Match returns the attributes captured from the path, or false if the pattern does not match.
+// Groups that did not participate in the match are omitted.
+func (p Pattern) Match(path string) (map[string]string, bool) {
+	path = filepath.ToSlash(path)
+	indexes := p.matcher.FindStringSubmatchIndex(path)
+	if indexes == nil {
+		return nil, false
+	}
+
+	attributes := make(map[string]string)
+	for i, name := range p.matcher.SubexpNames() {
+		if name == "" || indexes[2*i] < 0 {
+			continue
+		}
+		attributes[name] = path[indexes[2*i]:indexes[2*i+1]]
+	}
+	return attributes, true
+}
+
+// Derive returns the attributes captured by the first pattern matching the path.
+// It returns nil if no pattern matches.
+func Derive(path string, patterns []Pattern) map[string]string {
+	for _, pattern := range patterns {
+		if attributes, ok := pattern.Match(path); ok {
+			return attributes
+		}
+	}
+	return nil
+}
+
+// ParseFilter parses a filter in the form key=value
+func ParseFilter(filter string) (Filter, error) {
+	key, value, found := strings.Cut(filter, "=")
+	if !found || key == "" {
+		return Filter{}, fmt.Errorf("invalid filter %s: expected <key>=<value>", filter)
+	}
+	return Filter{Key: key, Value: value}, nil
+}
+
+// MatchFilters reports whether the attributes satisfy the filters. Filters on different keys
+// must all match, while filters on the same key match if any of their values matches.
+func MatchFilters(attributes map[string]string, filters []Filter) bool {
+	values := make(map[string][]string)
+	for _, filter := range filters {
+		values[filter.Key] = append(values[filter.Key], filter.Value)
+	}
+
+	for key, allowed := range values {
+		value, exists := attributes[key]
+		if !exists || !slices.Contains(allowed, value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package attribute
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		path      string
+		expected  map[string]string
+		matches   bool
+		expectErr bool
+	}{
+		{
+			name:     "Glob with placeholders",
+			pattern:  "environments/{org}/{service}/{env}",
+			path:     "environments/org-1/service-1/prod",
+			expected: map[string]string{"org": "org-1", "service": "service-1", "env": "prod"},
+			matches:  true,
+		},
+		{
+			name:     "Glob with wildcard",
+			pattern:  "environments/*/{service}/{env}",
+			path:     "environments/org-1/service-1/dev",
+			expected: map[string]string{"service": "service-1", "env": "dev"},
+			matches:  true,
+		},
+		{
+			name:    "Glob does not match deeper paths",
+			pattern: "environments/{org}/{env}",
+			path:    "environments/org-1/service-1/dev",
+		},
+		{
+			name:     "Glob with literal characters",
+			pattern:  "stacks/{name}.d",
+			path:     "stacks/network.d",
+			expected: map[string]string{"name": "network"},
+			matches:  true,
+		},
+		{
+			name:     "Regular expression",
+			pattern:  `^envs/(?P<env>prod|dev)(?:/(?P<region>[a-z0-9-]+))?$`,
+			path:     "envs/prod",
+			expected: map[string]string{"env": "prod"},
+			matches:  true,
+		},
+		{
+			name:    "Regular expression does not match",
+			pattern: `^envs/(?P<env>prod|dev)$`,
+			path:    "envs/staging",
+		},
+		{
+			name:      "Invalid regular expression",
+			pattern:   `^envs/(?P<env>prod`,
+			expectErr: true,
+		},
+		{
+			name:      "No attributes",
+			pattern:   "environments/*",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := ParsePattern(tt.pattern)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			attributes, matches := pattern.Match(tt.path)
+			if matches != tt.matches {
+				t.Fatalf("Expected match %t, got %t", tt.matches, matches)
+			}
+			if !reflect.DeepEqual(attributes, tt.expected) {
+				t.Errorf("Expected attributes %v, got %v", tt.expected, attributes)
+			}
+		})
+	}
+}
+
+func TestDerive(t *testing.T) {
+	patterns := make([]Pattern, 0)
+	for _, p := range []string{"environments/{org}/{service}/{env}", "environments/{org}/{env}"} {
+		pattern, err := ParsePattern(p)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	if attributes := Derive("environments/org-1/dev", patterns); !reflect.DeepEqual(attributes, map[string]string{"org": "org-1", "env": "dev"}) {
+		t.Errorf("Unexpected attributes %v", attributes)
+	}
+	if attributes := Derive("modules/core", patterns); attributes != nil {
+		t.Errorf("Expected no attributes, got %v", attributes)
+	}
+}
+
+func TestMatchFilters(t *testing.T) {
+	attributes := map[string]string{"org": "org-1", "env": "prod"}
+
+	tests := []struct {
+		name     string
+		filters  []string
+		expected bool
+	}{
+		{name: "No filters", expected: true},
+		{name: "Matching filter", filters: []string{"env=prod"}, expected: true},
+		{name: "Any value of the same key", filters: []string{"env=dev", "env=prod"}, expected: true},
+		{name: "All keys", filters: []string{"env=prod", "org=org-2"}, expected: false},
+		{name: "Missing attribute", filters: []string{"region=eu"}, expected: false},
+		{name: "Empty value", filters: []string{"env="}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := make([]Filter, 0)
+			for _, f := range tt.filters {
+				filter, err := ParseFilter(f)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				filters = append(filters, filter)
+			}
+			if matches := MatchFilters(attributes, filters); matches != tt.expected {
+				t.Errorf("Expected %t, got %t", tt.expected, matches)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"env", "=prod"} {
+		if _, err := ParseFilter(invalid); err == nil {
+			t.Errorf("Expected error for filter %s", invalid)
+		}
+	}
+}
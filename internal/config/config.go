@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the name of the configuration file looked up in the base path
+const DefaultFileName = ".tf-mod-watcher.yaml"
+
+// Config is the repository configuration of tf-mod-watcher
+type Config struct {
+	Layers []Layer `yaml:"layers"`
+}
+
+// Layer is a tier of modules and the tiers its modules may use
+type Layer struct {
+	Name  string   `yaml:"name"`
+	Paths []string `yaml:"paths"` // Globs matching module paths relative to the base path
+	Allow []string `yaml:"allow"` // Names of the other layers the modules of the layer may use
+}
+
+// Load reads the configuration file. Unknown keys are rejected, so that typos do not silently disable rules.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	config := &Config{}
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return config, nil
+}
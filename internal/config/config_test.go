@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    *Config
+		shouldError bool
+	}{
+		{
+			name: "Layers",
+			content: `layers:
+  - name: environments
+    paths: ["environments/**"]
+    allow: [modules]
+  - name: modules
+    paths: ["modules/**"]
+`,
+			expected: &Config{Layers: []Layer{
+				{Name: "environments", Paths: []string{"environments/**"}, Allow: []string{"modules"}},
+				{Name: "modules", Paths: []string{"modules/**"}},
+			}},
+		},
+		{
+			name:     "Empty file",
+			content:  "",
+			expected: &Config{},
+		},
+		{
+			name: "Unknown key",
+			content: `layers:
+  - name: modules
+    path: ["modules/**"]
+`,
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), DefaultFileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			config, err := Load(path)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(config, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, config)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
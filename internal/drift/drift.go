@@ -0,0 +1,182 @@
+package drift
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+	"github.com/hurack3034217/tf-mod-watcher/internal/terraform"
+)
+
+// Statuses of a usage relative to the newest version of its source
+const (
+	StatusNewest      = "newest"
+	StatusOutdated    = "outdated"
+	StatusUnversioned = "unversioned" // The ref is a branch or commit rather than a version, e.g. "main"
+	StatusUnpinned    = "unpinned"    // Neither a version argument nor a ref is set
+)
+
+// Usage is a module block calling a remote module
+type Usage struct {
+	Module  string // ID of the module containing the module block
+	Name    string // Label of the module block
+	File    string
+	Line    int
+	Version string // Version argument, or ref of the source if not set
+	Status  string
+}
+
+// Source is a remote module and every usage of it
+type Source struct {
+	Address  string   // Source without its ref
+	Newest   string   // Newest pinned version, empty if no usage is pinned to a version
+	Versions []string // Distinct pinned versions, newest first. Unversioned refs are not included.
+	Usages   []Usage  // Sorted by status, version and module
+}
+
+// Collect groups the module blocks calling remote modules by source, sorted by address
+func Collect(graph *modulegraph.Graph) []Source {
+	remote := make(map[string]struct{})
+	for _, node := range graph.Nodes {
+		if node.Kind == modulegraph.KindRemote {
+			remote[node.ID] = struct{}{}
+		}
+	}
+
+	usages := make(map[string][]Usage)
+	for _, edge := range graph.Edges {
+		if _, ok := remote[edge.To]; !ok {
+			continue
+		}
+		address, ref := terraform.SplitRef(edge.Source)
+		usages[address] = append(usages[address], Usage{
+			Module:  edge.From,
+			Name:    edge.Name,
+			File:    edge.File,
+			Line:    edge.Line,
+			Version: cmp.Or(edge.Version, ref),
+		})
+	}
+
+	sources := make([]Source, 0, len(usages))
+	for address, sourceUsages := range usages {
+		versions := make([]string, 0)
+		for _, usage := range sourceUsages {
+			if IsVersion(usage.Version) && !slices.Contains(versions, usage.Version) {
+				versions = append(versions, usage.Version)
+			}
+		}
+		slices.SortFunc(versions, func(a, b string) int {
+			return cmp.Or(CompareVersions(b, a), cmp.Compare(a, b))
+		})
+
+		source := Source{Address: address, Versions: versions, Usages: sourceUsages}
+		if len(versions) > 0 {
+			source.Newest = versions[0]
+		}
+		for i := range source.Usages {
+			switch {
+			case source.Usages[i].Version == "":
+				source.Usages[i].Status = StatusUnpinned
+			case !IsVersion(source.Usages[i].Version):
+				source.Usages[i].Status = StatusUnversioned
+			case CompareVersions(source.Usages[i].Version, source.Newest) < 0:
+				source.Usages[i].Status = StatusOutdated
+			default:
+				source.Usages[i].Status = StatusNewest
+			}
+		}
+		slices.SortFunc(source.Usages, func(a, b Usage) int {
+			return cmp.Or(
+				cmp.Compare(statusOrder(a.Status), statusOrder(b.Status)),
+				CompareVersions(b.Version, a.Version),
+				cmp.Compare(a.Module, b.Module),
+				cmp.Compare(a.Name, b.Name),
+			)
+		})
+		sources = append(sources, source)
+	}
+
+	slices.SortFunc(sources, func(a, b Source) int {
+		return cmp.Compare(a.Address, b.Address)
+	})
+	return sources
+}
+
+// statusOrder lists the newest usages first
+func statusOrder(status string) int {
+	return slices.Index([]string{StatusNewest, StatusOutdated, StatusUnversioned, StatusUnpinned}, status)
+}
+
+// IsVersion reports whether the version argument or ref is a version, such as "5.1.0", "v5.1.0" or "~> 5.1",
+// rather than a branch or commit
+func IsVersion(version string) bool {
+	release, _ := splitVersion(version)
+	for _, segment := range release {
+		if _, err := strconv.Atoi(segment); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareVersions compares two versions or version constraints such as "5.1.0", "v5.1.0" or "~> 5.1"
+// by their first version number. Numeric segments compare numerically, missing segments count as 0,
+// and a pre-release is lower than its release. Values that are not versions are lower than versions.
+func CompareVersions(a, b string) int {
+	if aVersion, bVersion := IsVersion(a), IsVersion(b); !aVersion || !bVersion {
+		if aVersion == bVersion {
+			return cmp.Compare(a, b)
+		}
+		if aVersion {
+			return 1
+		}
+		return -1
+	}
+
+	aRelease, aPrerelease := splitVersion(a)
+	bRelease, bPrerelease := splitVersion(b)
+
+	for i := range max(len(aRelease), len(bRelease)) {
+		aSegment, bSegment := "0", "0"
+		if i < len(aRelease) {
+			aSegment = aRelease[i]
+		}
+		if i < len(bRelease) {
+			bSegment = bRelease[i]
+		}
+		if result := compareSegments(aSegment, bSegment); result != 0 {
+			return result
+		}
+	}
+
+	switch {
+	case aPrerelease == bPrerelease:
+		return 0
+	case aPrerelease == "":
+		return 1
+	case bPrerelease == "":
+		return -1
+	default:
+		return cmp.Compare(aPrerelease, bPrerelease)
+	}
+}
+
+// splitVersion returns the dot-separated release segments and the pre-release of the first version in a constraint
+func splitVersion(version string) ([]string, string) {
+	version, _, _ = strings.Cut(version, ",")
+	version = strings.TrimLeft(strings.TrimSpace(version), "=~<>! ")
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "+")
+	release, prerelease, _ := strings.Cut(version, "-")
+	return strings.Split(release, "."), prerelease
+}
+
+// compareSegments compares numeric version segments
+func compareSegments(a, b string) int {
+	aNumber, _ := strconv.Atoi(a)
+	bNumber, _ := strconv.Atoi(b)
+	return cmp.Compare(aNumber, bNumber)
+}
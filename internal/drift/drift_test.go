@@ -0,0 +1,108 @@
+package drift
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "5.1.0", b: "5.0.0", expected: 1},
+		{a: "5.9.0", b: "5.10.0", expected: -1},
+		{a: "v1.2.0", b: "1.2.0", expected: 0},
+		{a: "~> 5.1", b: "5.1.0", expected: 0},
+		{a: ">= 4.0, < 5.0", b: "3.9.9", expected: 1},
+		{a: "1.0.0-rc1", b: "1.0.0", expected: -1},
+		{a: "1.0.0-rc2", b: "1.0.0-rc1", expected: 1},
+		{a: "main", b: "v1.2.0", expected: -1},
+		{a: "0.1.0", b: "abc1234", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := CompareVersions(tt.a, tt.b); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIsVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected bool
+	}{
+		{version: "5.1.0", expected: true},
+		{version: "v1.2.0", expected: true},
+		{version: "~> 5.1", expected: true},
+		{version: "1.0.0-rc1", expected: true},
+		{version: "main", expected: false},
+		{version: "abc1234", expected: false},
+		{version: "release/1.0", expected: false},
+		{version: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := IsVersion(tt.version); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	graph := &modulegraph.Graph{
+		Nodes: []modulegraph.Node{
+			{ID: "envs/dev", Kind: modulegraph.KindRoot},
+			{ID: "envs/prod", Kind: modulegraph.KindRoot},
+			{ID: "git::https://example.com/bucket.git", Kind: modulegraph.KindRemote},
+			{ID: "git::https://example.com/bucket.git?ref=main", Kind: modulegraph.KindRemote},
+			{ID: "git::https://example.com/bucket.git?ref=v1.2.0", Kind: modulegraph.KindRemote},
+			{ID: "modules/network", Kind: modulegraph.KindChild},
+			{ID: "terraform-aws-modules/vpc/aws", Kind: modulegraph.KindRemote},
+		},
+		Edges: []modulegraph.Edge{
+			{From: "envs/dev", To: "modules/network", Name: "network", Source: "../../modules/network"},
+			{From: "envs/dev", To: "terraform-aws-modules/vpc/aws", Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0", File: "dev.tf", Line: 2},
+			{From: "envs/prod", To: "terraform-aws-modules/vpc/aws", Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Version: "5.10.0", File: "prod.tf", Line: 2},
+			{From: "envs/prod", To: "git::https://example.com/bucket.git", Name: "bucket", Source: "git::https://example.com/bucket.git", File: "prod.tf", Line: 6},
+			{From: "envs/dev", To: "git::https://example.com/bucket.git?ref=main", Name: "bucket", Source: "git::https://example.com/bucket.git?ref=main", File: "dev.tf", Line: 6},
+			{From: "modules/network", To: "terraform-aws-modules/vpc/aws", Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Version: "5.9.0", File: "network.tf", Line: 2},
+			{From: "modules/network", To: "git::https://example.com/bucket.git?ref=v1.2.0", Name: "bucket", Source: "git::https://example.com/bucket.git?ref=v1.2.0", File: "network.tf", Line: 6},
+		},
+	}
+
+	expected := []Source{
+		{
+			Address:  "git::https://example.com/bucket.git",
+			Newest:   "v1.2.0",
+			Versions: []string{"v1.2.0"},
+			Usages: []Usage{
+				{Module: "modules/network", Name: "bucket", File: "network.tf", Line: 6, Version: "v1.2.0", Status: StatusNewest},
+				{Module: "envs/dev", Name: "bucket", File: "dev.tf", Line: 6, Version: "main", Status: StatusUnversioned},
+				{Module: "envs/prod", Name: "bucket", File: "prod.tf", Line: 6, Status: StatusUnpinned},
+			},
+		},
+		{
+			Address:  "terraform-aws-modules/vpc/aws",
+			Newest:   "5.10.0",
+			Versions: []string{"5.10.0", "5.9.0", "5.0.0"},
+			Usages: []Usage{
+				{Module: "envs/prod", Name: "vpc", File: "prod.tf", Line: 2, Version: "5.10.0", Status: StatusNewest},
+				{Module: "modules/network", Name: "vpc", File: "network.tf", Line: 2, Version: "5.9.0", Status: StatusOutdated},
+				{Module: "envs/dev", Name: "vpc", File: "dev.tf", Line: 2, Version: "5.0.0", Status: StatusOutdated},
+			},
+		},
+	}
+
+	sources := Collect(graph)
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, sources)
+	}
+}
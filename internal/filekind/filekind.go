@@ -0,0 +1,83 @@
+package filekind
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/terraform"
+)
+
+// Kinds of changed files
+const (
+	KindConfig  = "config"  // Terraform configuration, e.g. main.tf
+	KindVars    = "vars"    // Variable definitions, e.g. prod.tfvars
+	KindLock    = "lock"    // Dependency lock file
+	KindVersion = "version" // Version files such as .terraform-version
+	KindAssets  = "assets"  // Templates and other files read by the configuration
+	KindDocs    = "docs"    // Documentation, e.g. README.md
+	KindTests   = "tests"   // Terraform tests, e.g. main.tftest.hcl
+)
+
+// docNames lists the base names without extension of documentation files
+var docNames = []string{"readme", "changelog", "license", "codeowners", "contributing"}
+
+// docExtensions lists the extensions of documentation markup. Plain text files are often read
+// by file() or templatefile() and are assets.
+var docExtensions = []string{".md", ".markdown", ".rst", ".adoc"}
+
+// Kinds returns every kind in the order they are reported
+func Kinds() []string {
+	return []string{KindConfig, KindVars, KindLock, KindVersion, KindAssets, KindDocs, KindTests}
+}
+
+// Classify returns the kind of the file at the given path. Files of no other kind are assets.
+func Classify(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case name == terraform.LockFileName:
+		return KindLock
+	case terraform.IsVersionFile(path):
+		return KindVersion
+	case strings.HasSuffix(name, ".tftest.hcl"), strings.HasSuffix(name, ".tftest.json"):
+		return KindTests
+	case strings.HasSuffix(name, ".tf"), strings.HasSuffix(name, ".tf.json"):
+		return KindConfig
+	case strings.HasSuffix(name, ".tfvars"), strings.HasSuffix(name, ".tfvars.json"):
+		return KindVars
+	case slices.Contains(docExtensions, filepath.Ext(name)),
+		slices.Contains(docNames, strings.TrimSuffix(name, filepath.Ext(name))):
+		return KindDocs
+	default:
+		return KindAssets
+	}
+}
+
+// Of returns the distinct kinds of the files in the order of Kinds
+func Of(files []string) []string {
+	kinds := make([]string, 0)
+	for _, kind := range Kinds() {
+		if slices.ContainsFunc(files, func(file string) bool { return Classify(file) == kind }) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// ParseKinds parses kinds given as separate values or comma-separated lists
+func ParseKinds(values []string) ([]string, error) {
+	kinds := make([]string, 0)
+	for _, value := range values {
+		for kind := range strings.SplitSeq(value, ",") {
+			kind = strings.TrimSpace(kind)
+			if !slices.Contains(Kinds(), kind) {
+				return nil, fmt.Errorf("invalid change kind %s: expected one of %s", kind, strings.Join(Kinds(), ", "))
+			}
+			if !slices.Contains(kinds, kind) {
+				kinds = append(kinds, kind)
+			}
+		}
+	}
+	return kinds, nil
+}
@@ -0,0 +1,92 @@
+package filekind
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/repo/envs/prod/main.tf", expected: KindConfig},
+		{path: "/repo/envs/prod/override.tf.json", expected: KindConfig},
+		{path: "/repo/envs/prod/env/prod.tfvars", expected: KindVars},
+		{path: "/repo/envs/prod/terraform.tfvars.json", expected: KindVars},
+		{path: "/repo/envs/prod/.terraform.lock.hcl", expected: KindLock},
+		{path: "/repo/.terraform-version", expected: KindVersion},
+		{path: "/repo/envs/.tool-versions", expected: KindVersion},
+		{path: "/repo/modules/app/templates/user_data.sh.tftpl", expected: KindAssets},
+		{path: "/repo/modules/app/policy.json", expected: KindAssets},
+		{path: "/repo/modules/app/banner.txt", expected: KindAssets},
+		{path: "/repo/modules/app/README.md", expected: KindDocs},
+		{path: "/repo/modules/app/LICENSE", expected: KindDocs},
+		{path: "/repo/modules/app/LICENSE.txt", expected: KindDocs},
+		{path: "/repo/docs/guide.adoc", expected: KindDocs},
+		{path: "/repo/modules/app/main.tftest.hcl", expected: KindTests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Classify(tt.path); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestOf(t *testing.T) {
+	kinds := Of([]string{"/repo/README.md", "/repo/main.tf", "/repo/.terraform.lock.hcl", "/repo/variables.tf"})
+	expected := []string{KindConfig, KindLock, KindDocs}
+	if !slices.Equal(kinds, expected) {
+		t.Errorf("Expected %v, got %v", expected, kinds)
+	}
+
+	if kinds := Of(nil); len(kinds) != 0 {
+		t.Errorf("Expected no kinds, got %v", kinds)
+	}
+}
+
+func TestParseKinds(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []string
+		expected    []string
+		shouldError bool
+	}{
+		{
+			name:     "Comma-separated",
+			values:   []string{"config,vars"},
+			expected: []string{KindConfig, KindVars},
+		},
+		{
+			name:     "Separate values with duplicates",
+			values:   []string{"lock", "config, lock"},
+			expected: []string{KindLock, KindConfig},
+		},
+		{
+			name:        "Unknown kind",
+			values:      []string{"config,code"},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kinds, err := ParseKinds(tt.values)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !slices.Equal(kinds, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, kinds)
+			}
+		})
+	}
+}
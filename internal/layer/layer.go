@@ -0,0 +1,128 @@
+package layer
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/config"
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+)
+
+// Rules decides which layers the modules of each layer may use
+type Rules struct {
+	layers []config.Layer
+}
+
+// Violation is a module block using a module of a layer its layer may not use
+type Violation struct {
+	Edge      modulegraph.Edge
+	FromLayer string
+	ToLayer   string
+}
+
+// NewRules validates the layers of the configuration
+func NewRules(layers []config.Layer) (*Rules, error) {
+	names := make([]string, 0, len(layers))
+	for _, l := range layers {
+		if l.Name == "" {
+			return nil, errors.New("invalid layer: name must not be empty")
+		}
+		if slices.Contains(names, l.Name) {
+			return nil, fmt.Errorf("invalid layer %s: defined more than once", l.Name)
+		}
+		if len(l.Paths) == 0 {
+			return nil, fmt.Errorf("invalid layer %s: paths must not be empty", l.Name)
+		}
+		for _, pattern := range l.Paths {
+			for _, segment := range strings.Split(pattern, "/") {
+				if _, err := path.Match(segment, ""); err != nil {
+					return nil, fmt.Errorf("invalid layer %s: invalid path %s: %w", l.Name, pattern, err)
+				}
+			}
+		}
+		names = append(names, l.Name)
+	}
+
+	for _, l := range layers {
+		for _, allowed := range l.Allow {
+			if !slices.Contains(names, allowed) {
+				return nil, fmt.Errorf("invalid layer %s: allows unknown layer %s", l.Name, allowed)
+			}
+		}
+	}
+
+	return &Rules{layers: layers}, nil
+}
+
+// LayerOf returns the name of the first layer with a path matching the module path relative to the base path
+func (r *Rules) LayerOf(modulePath string) (string, bool) {
+	for _, l := range r.layers {
+		for _, pattern := range l.Paths {
+			if matchGlob(strings.Split(pattern, "/"), strings.Split(modulePath, "/")) {
+				return l.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Allowed reports whether modules of the layer from may use modules of the layer to.
+// Modules may always use modules of their own layer.
+func (r *Rules) Allowed(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, l := range r.layers {
+		if l.Name == from {
+			return slices.Contains(l.Allow, to)
+		}
+	}
+	return false
+}
+
+// Check returns the edges between local modules that the rules forbid, in the order of the graph.
+// Modules outside every layer and remote modules are not constrained.
+func (r *Rules) Check(graph *modulegraph.Graph) []Violation {
+	layerOf := make(map[string]string)
+	for _, node := range graph.Nodes {
+		if node.Kind == modulegraph.KindRemote {
+			continue
+		}
+		if name, ok := r.LayerOf(node.ID); ok {
+			layerOf[node.ID] = name
+		}
+	}
+
+	violations := make([]Violation, 0)
+	for _, edge := range graph.Edges {
+		from, fromOK := layerOf[edge.From]
+		to, toOK := layerOf[edge.To]
+		if fromOK && toOK && !r.Allowed(from, to) {
+			violations = append(violations, Violation{Edge: edge, FromLayer: from, ToLayer: to})
+		}
+	}
+	return violations
+}
+
+// matchGlob matches path segments against glob segments, where ** matches any number of segments
+func matchGlob(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlob(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, err := path.Match(pattern[0], segments[0])
+	return err == nil && matched && matchGlob(pattern[1:], segments[1:])
+}
@@ -0,0 +1,103 @@
+package layer
+
+import (
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/config"
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+)
+
+var testLayers = []config.Layer{
+	{Name: "environments", Paths: []string{"environments/**"}, Allow: []string{"usecases", "modules"}},
+	{Name: "usecases", Paths: []string{"usecases/*"}, Allow: []string{"modules"}},
+	{Name: "modules", Paths: []string{"modules/**"}},
+}
+
+func TestNewRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers []config.Layer
+	}{
+		{name: "Empty name", layers: []config.Layer{{Paths: []string{"modules/**"}}}},
+		{name: "Duplicate name", layers: []config.Layer{{Name: "a", Paths: []string{"a"}}, {Name: "a", Paths: []string{"b"}}}},
+		{name: "No paths", layers: []config.Layer{{Name: "a"}}},
+		{name: "Invalid path", layers: []config.Layer{{Name: "a", Paths: []string{"a/["}}}},
+		{name: "Unknown allowed layer", layers: []config.Layer{{Name: "a", Paths: []string{"a"}, Allow: []string{"b"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRules(tt.layers); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestLayerOf(t *testing.T) {
+	rules, err := NewRules(testLayers)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "environments/organization-1/common/dev", expected: "environments"},
+		{path: "environments", expected: "environments"},
+		{path: "usecases/common", expected: "usecases"},
+		{path: "usecases/common/nested", expected: ""},
+		{path: "modules/common/common-1", expected: "modules"},
+		{path: "scripts/terraform", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			name, _ := rules.LayerOf(tt.path)
+			if name != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, name)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	rules, err := NewRules(testLayers)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	graph := &modulegraph.Graph{
+		Nodes: []modulegraph.Node{
+			{ID: "environments/dev", Kind: modulegraph.KindRoot},
+			{ID: "hashicorp/consul/aws", Kind: modulegraph.KindRemote},
+			{ID: "modules/common", Kind: modulegraph.KindChild},
+			{ID: "modules/service", Kind: modulegraph.KindChild},
+			{ID: "scripts/helper", Kind: modulegraph.KindChild},
+			{ID: "usecases/common", Kind: modulegraph.KindChild},
+		},
+		Edges: []modulegraph.Edge{
+			{From: "environments/dev", To: "usecases/common", Name: "common"},
+			{From: "modules/service", To: "modules/common", Name: "common"},
+			{From: "modules/service", To: "usecases/common", Name: "usecase"},
+			{From: "modules/service", To: "scripts/helper", Name: "helper"},
+			{From: "modules/service", To: "hashicorp/consul/aws", Name: "consul"},
+			{From: "usecases/common", To: "environments/dev", Name: "env"},
+		},
+	}
+
+	violations := rules.Check(graph)
+	expected := []Violation{
+		{Edge: graph.Edges[2], FromLayer: "modules", ToLayer: "usecases"},
+		{Edge: graph.Edges[5], FromLayer: "usecases", ToLayer: "environments"},
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("Expected %d violations, got %+v", len(expected), violations)
+	}
+	for i := range expected {
+		if violations[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], violations[i])
+		}
+	}
+}
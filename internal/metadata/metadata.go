@@ -0,0 +1,159 @@
+package metadata
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/terraform"
+)
+
+// Unknown marks values that cannot be determined statically,
+// e.g. values supplied with -backend-config or environment variables
+const Unknown = "unknown"
+
+// defaultCloudHostname is the hostname used by the cloud block when none is configured
+const defaultCloudHostname = "app.terraform.io"
+
+// Root holds the statically extracted metadata of a root module
+type Root struct {
+	Backend *Backend `json:"backend,omitempty"`
+	Cloud   *Cloud   `json:"cloud,omitempty"`
+	Version *Version `json:"version,omitempty"`
+}
+
+// Backend holds the backend settings of a root module
+type Backend struct {
+	Type   string            `json:"type"`
+	Config map[string]string `json:"config"`
+}
+
+// Cloud holds the HCP Terraform settings of a root module
+type Cloud struct {
+	Organization string          `json:"organization"`
+	Hostname     string          `json:"hostname"`
+	Workspaces   CloudWorkspaces `json:"workspaces"`
+}
+
+// CloudWorkspaces holds the workspace selection of a cloud block
+type CloudWorkspaces struct {
+	Name    string   `json:"name,omitempty"`
+	Project string   `json:"project,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// Version holds the CLI version constraint of a root module and where it is declared
+type Version struct {
+	Tool       string `json:"tool,omitempty"`
+	Constraint string `json:"constraint"`
+	Source     string `json:"source"` // Relative to the base path
+}
+
+// Collect extracts the metadata of the root module in the given directory.
+// Paths in the metadata are relative to basePath.
+func Collect(rootModuleDir, basePath string) (*Root, error) {
+	root := &Root{}
+
+	version, err := terraform.ResolveVersion(rootModuleDir, basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve version: %w", err)
+	}
+	if version != nil {
+		root.Version, err = newVersion(version, basePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cloud, err := terraform.FindCloud(rootModuleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cloud settings: %w", err)
+	}
+	if cloud != nil {
+		root.Cloud = newCloud(cloud)
+		return root, nil
+	}
+
+	backend, err := terraform.FindBackend(rootModuleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find backend: %w", err)
+	}
+	if backend == nil {
+		// Terraform falls back to the local backend when none is configured
+		backend = &terraform.Backend{Type: "local", Config: map[string]string{"path": "terraform.tfstate"}}
+	}
+	root.Backend = newBackend(backend)
+
+	return root, nil
+}
+
+// newBackend converts extracted backend settings, marking values that are not known statically
+func newBackend(backend *terraform.Backend) *Backend {
+	config := make(map[string]string, len(backend.Config))
+	for name, value := range backend.Config {
+		config[name] = value
+	}
+	for _, name := range backend.Unknown {
+		config[name] = Unknown
+	}
+	for _, name := range backend.MissingAttributes() {
+		config[name] = Unknown
+	}
+
+	return &Backend{
+		Type:   backend.Type,
+		Config: config,
+	}
+}
+
+// newCloud converts extracted cloud settings, marking values that are not known statically
+func newCloud(cloud *terraform.Cloud) *Cloud {
+	// knownOr returns value unless the attribute is not a literal or value is empty
+	knownOr := func(name, value, fallback string) string {
+		if slices.Contains(cloud.Unknown, name) {
+			return Unknown
+		}
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+
+	workspaces := CloudWorkspaces{
+		Name:    knownOr("workspaces.name", cloud.WorkspaceName, ""),
+		Project: knownOr("workspaces.project", cloud.Project, ""),
+		Tags:    slices.Clone(cloud.WorkspaceTags),
+	}
+	if slices.Contains(cloud.Unknown, "workspaces.tags") {
+		workspaces.Tags = []string{Unknown}
+	}
+	// Without a name or tags the workspace is selected with TF_WORKSPACE
+	if workspaces.Name == "" && len(workspaces.Tags) == 0 {
+		workspaces.Name = Unknown
+	}
+
+	return &Cloud{
+		// The organization falls back to TF_CLOUD_ORGANIZATION when not configured
+		Organization: knownOr("organization", cloud.Organization, Unknown),
+		Hostname:     knownOr("hostname", cloud.Hostname, defaultCloudHostname),
+		Workspaces:   workspaces,
+	}
+}
+
+// newVersion converts a resolved version, making its source relative to basePath
+func newVersion(version *terraform.Version, basePath string) (*Version, error) {
+	absBasePath, err := filepath.Abs(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", basePath, err)
+	}
+	source, err := filepath.Rel(absBasePath, version.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute relative path of %s: %w", version.Source, err)
+	}
+
+	return &Version{
+		Tool:       version.Tool,
+		Constraint: version.Constraint,
+		Source:     source,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestCollect(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected *Root
+	}{
+		{
+			name: "Partial S3 backend",
+			files: map[string]string{
+				"backend.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    region = var.region
+  }
+}`,
+			},
+			expected: &Root{
+				Backend: &Backend{
+					Type: "s3",
+					Config: map[string]string{
+						"bucket": "tfstate",
+						"key":    Unknown,
+						"region": Unknown,
+					},
+				},
+			},
+		},
+		{
+			name: "Implicit local backend",
+			files: map[string]string{
+				"main.tf": `resource "null_resource" "this" {}`,
+			},
+			expected: &Root{
+				Backend: &Backend{
+					Type:   "local",
+					Config: map[string]string{"path": "terraform.tfstate"},
+				},
+			},
+		},
+		{
+			name: "Required version",
+			files: map[string]string{
+				"versions.tf": `terraform {
+  required_version = "~> 1.6.0"
+}`,
+			},
+			expected: &Root{
+				Backend: &Backend{
+					Type:   "local",
+					Config: map[string]string{"path": "terraform.tfstate"},
+				},
+				Version: &Version{
+					Constraint: "~> 1.6.0",
+					Source:     "versions.tf",
+				},
+			},
+		},
+		{
+			name: "Cloud block with tags",
+			files: map[string]string{
+				"main.tf": `terraform {
+  cloud {
+    workspaces {
+      project = "infra"
+      tags    = ["network"]
+    }
+  }
+}`,
+			},
+			expected: &Root{
+				Cloud: &Cloud{
+					Organization: Unknown,
+					Hostname:     "app.terraform.io",
+					Workspaces: CloudWorkspaces{
+						Project: "infra",
+						Tags:    []string{"network"},
+					},
+				},
+			},
+		},
+		{
+			name: "Cloud block without workspace selection",
+			files: map[string]string{
+				"main.tf": `terraform {
+  cloud {
+    organization = "example"
+    hostname     = "tfe.example.com"
+  }
+}`,
+			},
+			expected: &Root{
+				Cloud: &Cloud{
+					Organization: "example",
+					Hostname:     "tfe.example.com",
+					Workspaces: CloudWorkspaces{
+						Name: Unknown,
+						Tags: []string{},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			testutil.WriteFiles(t, dir, tt.files)
+
+			root, err := Collect(dir, dir)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(root, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, root)
+			}
+		})
+	}
+}
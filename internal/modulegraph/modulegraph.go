@@ -0,0 +1,345 @@
+package modulegraph
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/terraform"
+)
+
+// Kinds of modules in the graph
+const (
+	KindRoot   = "root"   // Discovered root module
+	KindChild  = "child"  // Local module called by another module
+	KindRemote = "remote" // Module from a registry, git or any other non-local source
+)
+
+// Supported output formats
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+	FormatJSON    = "json"
+)
+
+// Node is a module in the graph
+type Node struct {
+	ID          string `json:"id"` // Path relative to the base path for local modules, source for remote modules
+	Kind        string `json:"kind"`
+	Highlighted bool   `json:"highlighted,omitempty"`
+	Dir         string `json:"-"` // Absolute path of a local module
+}
+
+// Edge is a module block calling a module
+type Edge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Name    string `json:"name"`              // Label of the module block
+	Source  string `json:"source"`            // Source as written in the module block
+	Version string `json:"version,omitempty"` // Version constraint of a registry module
+	File    string `json:"-"`                 // Path of the file containing the module block
+	Line    int    `json:"-"`                 // Line of the source attribute
+}
+
+// Graph holds the modules reachable from the root modules and the module blocks between them
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Formats returns the supported output formats
+func Formats() []string {
+	return []string{FormatDOT, FormatMermaid, FormatJSON}
+}
+
+// Build walks the module blocks from the given root modules. Local module IDs are relative to basePath.
+// Modules that cannot be parsed are logged and kept in the graph without edges.
+// Nodes are sorted by ID and edges by their module and name, so the result only depends on the files.
+func Build(rootModuleDirs []string, basePath string, logger *slog.Logger) (*Graph, error) {
+	absBasePath, err := filepath.Abs(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", basePath, err)
+	}
+
+	nodes := make(map[string]*Node)
+	localNode := func(dir, kind string) (*Node, error) {
+		relPath, err := filepath.Rel(absBasePath, dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute relative path of %s: %w", dir, err)
+		}
+		id := filepath.ToSlash(relPath)
+		node, exists := nodes[id]
+		if !exists {
+			node = &Node{ID: id, Kind: kind, Dir: dir}
+			nodes[id] = node
+		}
+		return node, nil
+	}
+
+	queue := make([]string, 0, len(rootModuleDirs))
+	for _, dir := range rootModuleDirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
+		}
+		node, err := localNode(absDir, KindRoot)
+		if err != nil {
+			return nil, err
+		}
+		node.Kind = KindRoot
+		queue = append(queue, absDir)
+	}
+
+	edges := make([]Edge, 0)
+	visited := make(map[string]struct{})
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if _, seen := visited[current]; seen {
+			continue
+		}
+		visited[current] = struct{}{}
+
+		from, err := localNode(current, KindChild)
+		if err != nil {
+			return nil, err
+		}
+		calls, err := terraform.FindModuleCalls(current)
+		if err != nil {
+			logger.Warn("Failed to find module calls", "module", current, "error", err)
+			continue
+		}
+
+		for _, call := range calls {
+			var to *Node
+			childDir := filepath.Join(current, call.Source)
+			switch {
+			case call.Dynamic:
+				logger.Debug("Skipping module with a non-literal source", "module", current, "name", call.Name)
+				continue
+			case filepath.IsAbs(call.Source):
+				logger.Warn("Skipping module with an absolute source", "module", current, "name", call.Name, "source", call.Source)
+				continue
+			case isDir(childDir):
+				to, err = localNode(childDir, KindChild)
+				if err != nil {
+					return nil, err
+				}
+				queue = append(queue, childDir)
+			case terraform.IsLocalSource(call.Source):
+				logger.Warn("Local module source does not exist", "module", current, "name", call.Name, "source", call.Source)
+				continue
+			default:
+				// Like registry sources, whose version is a separate argument, git sources are one node for every ref
+				id, _ := terraform.SplitRef(call.Source)
+				to = nodes[id]
+				if to == nil {
+					to = &Node{ID: id, Kind: KindRemote}
+					nodes[id] = to
+				}
+			}
+			edges = append(edges, Edge{From: from.ID, To: to.ID, Name: call.Name, Source: call.Source, Version: call.Version, File: call.File, Line: call.Line})
+		}
+	}
+
+	g := &Graph{Nodes: make([]Node, 0, len(nodes)), Edges: edges}
+	for _, node := range nodes {
+		g.Nodes = append(g.Nodes, *node)
+	}
+	slices.SortFunc(g.Nodes, func(a, b Node) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	slices.SortFunc(g.Edges, func(a, b Edge) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.Name, b.Name))
+	})
+	return g, nil
+}
+
+// isDir reports whether the path is an existing directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// Highlight marks the local modules in the given directories
+func (g *Graph) Highlight(dirs []string) {
+	for i := range g.Nodes {
+		if g.Nodes[i].Dir != "" && slices.Contains(dirs, g.Nodes[i].Dir) {
+			g.Nodes[i].Highlighted = true
+		}
+	}
+}
+
+// Consumer is a root module calling a module, directly or through other modules
+type Consumer struct {
+	Root string   // ID of the root module
+	Path []string // IDs of the modules from the root module to the called module
+}
+
+// NodeID returns the ID of the local module in the given directory, or false if it is not in the graph
+func (g *Graph) NodeID(dir string) (string, bool) {
+	for _, node := range g.Nodes {
+		if node.Dir != "" && node.Dir == dir {
+			return node.ID, true
+		}
+	}
+	return "", false
+}
+
+// Consumers walks the graph backwards from the modules with the given IDs and returns every root module
+// reaching one of them, sorted by ID, with the shortest path to the nearest one.
+// A root module among the given modules consumes itself.
+func (g *Graph) Consumers(ids []string) []Consumer {
+	callers := make(map[string][]string)
+	for _, edge := range g.Edges {
+		callers[edge.To] = append(callers[edge.To], edge.From)
+	}
+
+	// Breadth-first search from the modules, recording the next module towards them
+	next := make(map[string]string)
+	queue := make([]string, 0)
+	for _, node := range g.Nodes {
+		if slices.Contains(ids, node.ID) {
+			next[node.ID] = ""
+			queue = append(queue, node.ID)
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, caller := range callers[current] {
+			if _, seen := next[caller]; seen {
+				continue
+			}
+			next[caller] = current
+			queue = append(queue, caller)
+		}
+	}
+
+	consumers := make([]Consumer, 0)
+	for _, node := range g.Nodes {
+		if _, reached := next[node.ID]; !reached || node.Kind != KindRoot {
+			continue
+		}
+		path := []string{node.ID}
+		for id := next[node.ID]; id != ""; id = next[id] {
+			path = append(path, id)
+		}
+		consumers = append(consumers, Consumer{Root: node.ID, Path: path})
+	}
+	return consumers
+}
+
+// Reachable returns the edges reachable from the module with the given ID, in the order of the graph
+func (g *Graph) Reachable(id string) []Edge {
+	visited := map[string]struct{}{id: {}}
+	queue := []string{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range g.Edges {
+			if _, seen := visited[edge.To]; edge.From == current && !seen {
+				visited[edge.To] = struct{}{}
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+
+	edges := make([]Edge, 0)
+	for _, edge := range g.Edges {
+		if _, reached := visited[edge.From]; reached {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// Render renders the graph in the given format
+func (g *Graph) Render(format string) ([]byte, error) {
+	switch format {
+	case FormatDOT:
+		return []byte(g.dot()), nil
+	case FormatMermaid:
+		return []byte(g.mermaid()), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal graph: %w", err)
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unsupported graph format: %s", format)
+	}
+}
+
+// dot renders the graph in the Graphviz DOT language
+func (g *Graph) dot() string {
+	shapes := map[string]string{KindRoot: "box", KindChild: "ellipse", KindRemote: "component"}
+
+	var out strings.Builder
+	out.WriteString("digraph modules {\n")
+	out.WriteString("  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&out, "  %s [shape=%s", dotQuote(node.ID), shapes[node.Kind])
+		if node.Highlighted {
+			out.WriteString(", style=filled, fillcolor=gold")
+		}
+		out.WriteString("];\n")
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&out, "  %s -> %s [label=%s];\n",
+			dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Name+"\n"+edge.Source))
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// dotQuote quotes a string as a DOT ID
+func dotQuote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// mermaid renders the graph as a Mermaid flowchart
+func (g *Graph) mermaid() string {
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	var out strings.Builder
+	out.WriteString("flowchart LR\n")
+	highlighted := make([]string, 0)
+	for _, node := range g.Nodes {
+		label := mermaidQuote(node.ID)
+		switch node.Kind {
+		case KindRoot:
+			fmt.Fprintf(&out, "  %s[%s]\n", ids[node.ID], label)
+		case KindChild:
+			fmt.Fprintf(&out, "  %s(%s)\n", ids[node.ID], label)
+		default:
+			fmt.Fprintf(&out, "  %s[[%s]]\n", ids[node.ID], label)
+		}
+		if node.Highlighted {
+			highlighted = append(highlighted, ids[node.ID])
+		}
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&out, "  %s -->|%s| %s\n", ids[edge.From], mermaidQuote(edge.Name+": "+edge.Source), ids[edge.To])
+	}
+	if len(highlighted) > 0 {
+		out.WriteString("  classDef highlighted fill:#ffd700\n")
+		fmt.Fprintf(&out, "  class %s highlighted\n", strings.Join(highlighted, ","))
+	}
+	return out.String()
+}
+
+// mermaidQuote quotes a string as a Mermaid label
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
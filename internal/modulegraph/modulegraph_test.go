@@ -0,0 +1,340 @@
+package modulegraph
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func buildTestGraph(t *testing.T) (*Graph, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "app" {
+  source = "../../modules/app"
+}
+module "consul" {
+  source = "hashicorp/consul/aws"
+}
+module "missing" {
+  source = "../../modules/missing"
+}`,
+		"envs/dev/main.tf": `module "app" {
+  source = "../../modules/app"
+}`,
+		"modules/app/main.tf": `module "db" {
+  source = "./db"
+}
+module "consul" {
+  source = "hashicorp/consul/aws"
+}`,
+		"modules/app/db/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	g, err := Build([]string{filepath.Join(dir, "envs", "prod"), filepath.Join(dir, "envs", "dev")}, dir, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return g, dir
+}
+
+func TestBuild(t *testing.T) {
+	g, dir := buildTestGraph(t)
+
+	expectedNodes := []Node{
+		{ID: "envs/dev", Kind: KindRoot, Dir: filepath.Join(dir, "envs", "dev")},
+		{ID: "envs/prod", Kind: KindRoot, Dir: filepath.Join(dir, "envs", "prod")},
+		{ID: "hashicorp/consul/aws", Kind: KindRemote},
+		{ID: "modules/app", Kind: KindChild, Dir: filepath.Join(dir, "modules", "app")},
+		{ID: "modules/app/db", Kind: KindChild, Dir: filepath.Join(dir, "modules", "app", "db")},
+	}
+	if !slices.Equal(g.Nodes, expectedNodes) {
+		t.Errorf("Expected nodes %v, got %v", expectedNodes, g.Nodes)
+	}
+
+	expectedEdges := []Edge{
+		{From: "envs/dev", To: "modules/app", Name: "app", Source: "../../modules/app"},
+		{From: "envs/prod", To: "modules/app", Name: "app", Source: "../../modules/app"},
+		{From: "envs/prod", To: "hashicorp/consul/aws", Name: "consul", Source: "hashicorp/consul/aws"},
+		{From: "modules/app", To: "hashicorp/consul/aws", Name: "consul", Source: "hashicorp/consul/aws"},
+		{From: "modules/app", To: "modules/app/db", Name: "db", Source: "./db"},
+	}
+	for i := range g.Edges {
+		if g.Edges[i].File != filepath.Join(dir, filepath.FromSlash(g.Edges[i].From), "main.tf") || g.Edges[i].Line == 0 {
+			t.Errorf("Unexpected location of edge %+v", g.Edges[i])
+		}
+		g.Edges[i].File, g.Edges[i].Line = "", 0
+	}
+	if !slices.Equal(g.Edges, expectedEdges) {
+		t.Errorf("Expected edges %v, got %v", expectedEdges, g.Edges)
+	}
+}
+
+func TestBuild_UnparsableModule(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/a/main.tf":    "module \"m\" {\n  source = \"../../modules/m\"\n}\n",
+		"envs/b/main.tf":    `resource "x" "y" {`,
+		"modules/m/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	// The broken root is kept without edges and does not affect the healthy one
+	g, err := Build([]string{filepath.Join(dir, "envs", "a"), filepath.Join(dir, "envs", "b")}, dir, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ids := make([]string, 0, len(g.Nodes))
+	for _, node := range g.Nodes {
+		ids = append(ids, node.ID)
+	}
+	if expected := []string{"envs/a", "envs/b", "modules/m"}; !slices.Equal(ids, expected) {
+		t.Errorf("Expected nodes %v, got %v", expected, ids)
+	}
+	if len(g.Edges) != 1 || g.Edges[0].From != "envs/a" || g.Edges[0].To != "modules/m" {
+		t.Errorf("Expected a single edge from envs/a to modules/m, got %+v", g.Edges)
+	}
+}
+
+func TestBuild_GitRefs(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/dev/main.tf":  "module \"bucket\" {\n  source = \"git::https://example.com/bucket.git?ref=v1.1.0\"\n}\n",
+		"envs/prod/main.tf": "module \"bucket\" {\n  source = \"git::https://example.com/bucket.git?ref=v1.2.0\"\n}\n",
+	})
+
+	// Git sources are one node for every ref, like registry sources for every version
+	g, err := Build([]string{filepath.Join(dir, "envs", "dev"), filepath.Join(dir, "envs", "prod")}, dir, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	remotes := make([]string, 0)
+	for _, node := range g.Nodes {
+		if node.Kind == KindRemote {
+			remotes = append(remotes, node.ID)
+		}
+	}
+	if expected := []string{"git::https://example.com/bucket.git"}; !slices.Equal(remotes, expected) {
+		t.Errorf("Expected remote nodes %v, got %v", expected, remotes)
+	}
+	for _, edge := range g.Edges {
+		if edge.To != "git::https://example.com/bucket.git" {
+			t.Errorf("Expected edge to the git source without ref, got %+v", edge)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	g, dir := buildTestGraph(t)
+	g.Highlight([]string{filepath.Join(dir, "envs", "prod"), filepath.Join(dir, "modules", "app")})
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: FormatDOT,
+			expected: `digraph modules {
+  rankdir=LR;
+  "envs/dev" [shape=box];
+  "envs/prod" [shape=box, style=filled, fillcolor=gold];
+  "hashicorp/consul/aws" [shape=component];
+  "modules/app" [shape=ellipse, style=filled, fillcolor=gold];
+  "modules/app/db" [shape=ellipse];
+  "envs/dev" -> "modules/app" [label="app\n../../modules/app"];
+  "envs/prod" -> "modules/app" [label="app\n../../modules/app"];
+  "envs/prod" -> "hashicorp/consul/aws" [label="consul\nhashicorp/consul/aws"];
+  "modules/app" -> "hashicorp/consul/aws" [label="consul\nhashicorp/consul/aws"];
+  "modules/app" -> "modules/app/db" [label="db\n./db"];
+}
+`,
+		},
+		{
+			format: FormatMermaid,
+			expected: `flowchart LR
+  n0["envs/dev"]
+  n1["envs/prod"]
+  n2[["hashicorp/consul/aws"]]
+  n3("modules/app")
+  n4("modules/app/db")
+  n0 -->|"app: ../../modules/app"| n3
+  n1 -->|"app: ../../modules/app"| n3
+  n1 -->|"consul: hashicorp/consul/aws"| n2
+  n3 -->|"consul: hashicorp/consul/aws"| n2
+  n3 -->|"db: ./db"| n4
+  classDef highlighted fill:#ffd700
+  class n1,n3 highlighted
+`,
+		},
+		{
+			format: FormatJSON,
+			expected: `{
+  "nodes": [
+    {
+      "id": "envs/dev",
+      "kind": "root"
+    },
+    {
+      "id": "envs/prod",
+      "kind": "root",
+      "highlighted": true
+    },
+    {
+      "id": "hashicorp/consul/aws",
+      "kind": "remote"
+    },
+    {
+      "id": "modules/app",
+      "kind": "child",
+      "highlighted": true
+    },
+    {
+      "id": "modules/app/db",
+      "kind": "child"
+    }
+  ],
+  "edges": [
+    {
+      "from": "envs/dev",
+      "to": "modules/app",
+      "name": "app",
+      "source": "../../modules/app"
+    },
+    {
+      "from": "envs/prod",
+      "to": "modules/app",
+      "name": "app",
+      "source": "../../modules/app"
+    },
+    {
+      "from": "envs/prod",
+      "to": "hashicorp/consul/aws",
+      "name": "consul",
+      "source": "hashicorp/consul/aws"
+    },
+    {
+      "from": "modules/app",
+      "to": "hashicorp/consul/aws",
+      "name": "consul",
+      "source": "hashicorp/consul/aws"
+    },
+    {
+      "from": "modules/app",
+      "to": "modules/app/db",
+      "name": "db",
+      "source": "./db"
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			output, err := g.Render(tt.format)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(output) != tt.expected {
+				t.Errorf("Expected output:\n%s\ngot:\n%s", tt.expected, output)
+			}
+		})
+	}
+
+	if _, err := g.Render("svg"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestConsumers(t *testing.T) {
+	g, dir := buildTestGraph(t)
+
+	tests := []struct {
+		name     string
+		ids      []string
+		expected []Consumer
+	}{
+		{
+			name: "Nested child module",
+			ids:  []string{"modules/app/db"},
+			expected: []Consumer{
+				{Root: "envs/dev", Path: []string{"envs/dev", "modules/app", "modules/app/db"}},
+				{Root: "envs/prod", Path: []string{"envs/prod", "modules/app", "modules/app/db"}},
+			},
+		},
+		{
+			name: "Shortest path to the nearest module",
+			ids:  []string{"modules/app/db", "modules/app"},
+			expected: []Consumer{
+				{Root: "envs/dev", Path: []string{"envs/dev", "modules/app"}},
+				{Root: "envs/prod", Path: []string{"envs/prod", "modules/app"}},
+			},
+		},
+		{
+			name: "Remote module",
+			ids:  []string{"hashicorp/consul/aws"},
+			expected: []Consumer{
+				{Root: "envs/dev", Path: []string{"envs/dev", "modules/app", "hashicorp/consul/aws"}},
+				{Root: "envs/prod", Path: []string{"envs/prod", "hashicorp/consul/aws"}},
+			},
+		},
+		{
+			name: "Root module",
+			ids:  []string{"envs/prod"},
+			expected: []Consumer{
+				{Root: "envs/prod", Path: []string{"envs/prod"}},
+			},
+		},
+		{
+			name:     "Unknown module",
+			ids:      []string{"modules/unused"},
+			expected: []Consumer{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumers := g.Consumers(tt.ids)
+			if !slices.EqualFunc(consumers, tt.expected, func(a, b Consumer) bool {
+				return a.Root == b.Root && slices.Equal(a.Path, b.Path)
+			}) {
+				t.Errorf("Expected %v, got %v", tt.expected, consumers)
+			}
+		})
+	}
+
+	if id, ok := g.NodeID(filepath.Join(dir, "modules", "app")); !ok || id != "modules/app" {
+		t.Errorf("Expected modules/app, got %q (found: %v)", id, ok)
+	}
+	if _, ok := g.NodeID(filepath.Join(dir, "modules", "unused")); ok {
+		t.Error("Expected unused module not to be found")
+	}
+}
+
+func TestReachable(t *testing.T) {
+	g, _ := buildTestGraph(t)
+
+	edges := g.Reachable("envs/dev")
+	expected := [][2]string{
+		{"envs/dev", "modules/app"},
+		{"modules/app", "hashicorp/consul/aws"},
+		{"modules/app", "modules/app/db"},
+	}
+	if len(edges) != len(expected) {
+		t.Fatalf("Expected %d edges, got %+v", len(expected), edges)
+	}
+	for i, edge := range edges {
+		if edge.From != expected[i][0] || edge.To != expected[i][1] {
+			t.Errorf("Expected edge %v, got %s -> %s", expected[i], edge.From, edge.To)
+		}
+	}
+
+	if edges := g.Reachable("modules/app/db"); len(edges) != 0 {
+		t.Errorf("Expected no edges from a leaf module, got %+v", edges)
+	}
+}
@@ -0,0 +1,98 @@
+package parity
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+	"github.com/hurack3034217/tf-mod-watcher/internal/terraform"
+)
+
+// Values of a module in a difference other than versions
+const (
+	ValuePresent = "present" // Local module or remote module without a version
+	ValueMissing = "missing" // The root module does not use the module
+)
+
+// Member is a root module in a group of sibling root modules
+type Member struct {
+	Name string // Value of the attribute that differs between the siblings, e.g. the environment
+	Root string // ID of the root module in the graph
+}
+
+// Difference is a module that the siblings do not use alike
+type Difference struct {
+	Module string            // Local module ID, or remote module source without its ref
+	Values map[string]string // Version, ValuePresent or ValueMissing, keyed by member name
+}
+
+// Composition returns the modules the root module uses, directly or through local modules, with their versions.
+// Remote modules are keyed by their source without the ref, which becomes their version unless a
+// version argument is set. Versions of a module used more than once are joined with commas.
+func Composition(graph *modulegraph.Graph, root string) map[string]string {
+	kinds := make(map[string]string, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		kinds[node.ID] = node.Kind
+	}
+
+	versions := make(map[string][]string)
+	for _, edge := range graph.Reachable(root) {
+		module, version := edge.To, ""
+		if kinds[edge.To] == modulegraph.KindRemote {
+			var ref string
+			module, ref = terraform.SplitRef(edge.Source)
+			version = cmp.Or(edge.Version, ref)
+		}
+		if _, exists := versions[module]; !exists {
+			versions[module] = make([]string, 0)
+		}
+		if version != "" && !slices.Contains(versions[module], version) {
+			versions[module] = append(versions[module], version)
+		}
+	}
+
+	composition := make(map[string]string, len(versions))
+	for module, moduleVersions := range versions {
+		if len(moduleVersions) == 0 {
+			composition[module] = ValuePresent
+			continue
+		}
+		slices.Sort(moduleVersions)
+		composition[module] = strings.Join(moduleVersions, ",")
+	}
+	return composition
+}
+
+// Compare returns the modules that are missing from some members or used with different versions, sorted by module
+func Compare(graph *modulegraph.Graph, members []Member) []Difference {
+	compositions := make(map[string]map[string]string, len(members))
+	modules := make([]string, 0)
+	for _, member := range members {
+		composition := Composition(graph, member.Root)
+		compositions[member.Name] = composition
+		modules = append(modules, slices.Collect(maps.Keys(composition))...)
+	}
+	slices.Sort(modules)
+	modules = slices.Compact(modules)
+
+	differences := make([]Difference, 0)
+	for _, module := range modules {
+		values := make(map[string]string, len(members))
+		for _, member := range members {
+			value, exists := compositions[member.Name][module]
+			if !exists {
+				value = ValueMissing
+			}
+			values[member.Name] = value
+		}
+
+		distinct := slices.Collect(maps.Values(values))
+		slices.Sort(distinct)
+		if len(slices.Compact(distinct)) > 1 {
+			differences = append(differences, Difference{Module: module, Values: values})
+		}
+	}
+	return differences
+}
@@ -0,0 +1,83 @@
+package parity
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+)
+
+func testGraph() *modulegraph.Graph {
+	return &modulegraph.Graph{
+		Nodes: []modulegraph.Node{
+			{ID: "envs/dev", Kind: modulegraph.KindRoot},
+			{ID: "envs/prod", Kind: modulegraph.KindRoot},
+			{ID: "envs/stg", Kind: modulegraph.KindRoot},
+			{ID: "git::https://example.com/vpc.git?ref=v1", Kind: modulegraph.KindRemote},
+			{ID: "git::https://example.com/vpc.git?ref=v2", Kind: modulegraph.KindRemote},
+			{ID: "hashicorp/consul/aws", Kind: modulegraph.KindRemote},
+			{ID: "modules/service", Kind: modulegraph.KindChild},
+			{ID: "modules/specific", Kind: modulegraph.KindChild},
+		},
+		Edges: []modulegraph.Edge{
+			{From: "envs/dev", To: "modules/service", Source: "../../modules/service"},
+			{From: "envs/dev", To: "git::https://example.com/vpc.git?ref=v1", Source: "git::https://example.com/vpc.git?ref=v1"},
+			{From: "envs/prod", To: "modules/service", Source: "../../modules/service"},
+			{From: "envs/prod", To: "modules/specific", Source: "../../modules/specific"},
+			{From: "envs/prod", To: "git::https://example.com/vpc.git?ref=v2", Source: "git::https://example.com/vpc.git?ref=v2"},
+			{From: "envs/stg", To: "modules/service", Source: "../../modules/service"},
+			{From: "envs/stg", To: "git::https://example.com/vpc.git?ref=v1", Source: "git::https://example.com/vpc.git?ref=v1"},
+			{From: "modules/service", To: "hashicorp/consul/aws", Source: "hashicorp/consul/aws", Version: "0.1.0"},
+		},
+	}
+}
+
+func TestComposition(t *testing.T) {
+	composition := Composition(testGraph(), "envs/prod")
+	expected := map[string]string{
+		"modules/service":                  ValuePresent,
+		"modules/specific":                 ValuePresent,
+		"git::https://example.com/vpc.git": "v2",
+		"hashicorp/consul/aws":             "0.1.0",
+	}
+	if !reflect.DeepEqual(composition, expected) {
+		t.Errorf("Expected %v, got %v", expected, composition)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		members  []Member
+		expected []Difference
+	}{
+		{
+			name: "Missing module and different versions",
+			members: []Member{
+				{Name: "dev", Root: "envs/dev"},
+				{Name: "prod", Root: "envs/prod"},
+			},
+			expected: []Difference{
+				{Module: "git::https://example.com/vpc.git", Values: map[string]string{"dev": "v1", "prod": "v2"}},
+				{Module: "modules/specific", Values: map[string]string{"dev": ValueMissing, "prod": ValuePresent}},
+			},
+		},
+		{
+			name: "Same composition",
+			members: []Member{
+				{Name: "dev", Root: "envs/dev"},
+				{Name: "stg", Root: "envs/stg"},
+			},
+			expected: []Difference{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			differences := Compare(testGraph(), tt.members)
+			if !reflect.DeepEqual(differences, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, differences)
+			}
+		})
+	}
+}
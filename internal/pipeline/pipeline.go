@@ -0,0 +1,235 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/metadata"
+)
+
+// Supported pipeline formats
+const (
+	FormatGitLab    = "gitlab"
+	FormatBuildkite = "buildkite"
+)
+
+// gitlabKeywords lists the top-level keys of a GitLab CI configuration that are not jobs
+var gitlabKeywords = []string{
+	"after_script", "before_script", "cache", "default", "image", "include", "services", "stages", "variables", "workflow",
+}
+
+// Job is a target the job template is rendered for
+type Job struct {
+	Path       string            // Path of the root module relative to the base path
+	Slug       string            // Path and workspace converted for use in job names
+	Workspace  string            // Empty if the root module has no workspaces
+	Attributes map[string]string // Attributes derived from the path of the root module
+	Metadata   *metadata.Root    // Backend, cloud and version settings of the root module
+	DependsOn  []string          // Slugs of the jobs that must finish before this one
+}
+
+// Formats returns the supported pipeline formats
+func Formats() []string {
+	return []string{FormatGitLab, FormatBuildkite}
+}
+
+// Generate renders the job template for every job and assembles a pipeline document.
+// Jobs must be given in dependency order. Dependencies between jobs are added to the rendered
+// jobs as needs (GitLab) or depends_on (Buildkite), in addition to any set by the template.
+func Generate(format string, tmpl *template.Template, jobs []Job) ([]byte, error) {
+	switch format {
+	case FormatGitLab:
+		return generateGitLab(tmpl, jobs)
+	case FormatBuildkite:
+		return generateBuildkite(tmpl, jobs)
+	default:
+		return nil, fmt.Errorf("unsupported pipeline format: %s", format)
+	}
+}
+
+// generateGitLab builds a child pipeline. The template must render a mapping of job names to jobs.
+func generateGitLab(tmpl *template.Template, jobs []Job) ([]byte, error) {
+	document := &yaml.Node{Kind: yaml.MappingNode}
+	jobNames := make(map[string][]string) // key: slug, value: names of the rendered jobs
+
+	for _, job := range jobs {
+		node, err := render(tmpl, job)
+		if err != nil {
+			return nil, err
+		}
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("job template for %s must render a mapping of jobs", job.Path)
+		}
+
+		for i := 0; i < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			isJob := isGitLabJob(key.Value, value)
+			if _, exists := lookup(document, key.Value); exists {
+				if !isJob {
+					// Global keywords and hidden templates rendered for every job only need to appear once
+					continue
+				}
+				return nil, fmt.Errorf("duplicate job %s rendered for %s", key.Value, job.Path)
+			}
+
+			if isJob {
+				var needs []string
+				for _, dependency := range job.DependsOn {
+					needs = append(needs, jobNames[dependency]...)
+				}
+				appendToSequence(value, "needs", needs)
+				jobNames[job.Slug] = append(jobNames[job.Slug], key.Value)
+			}
+			document.Content = append(document.Content, key, value)
+		}
+	}
+
+	if len(jobNames) == 0 {
+		// A child pipeline must contain at least one job
+		document.Content = append(document.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "no-changes"},
+			&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: "script"},
+				{Kind: yaml.SequenceNode, Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: "echo No root modules were updated"},
+				}},
+			}},
+		)
+	}
+
+	return encode(document)
+}
+
+// isGitLabJob reports whether a top-level entry of a GitLab CI configuration is a job
+func isGitLabJob(name string, value *yaml.Node) bool {
+	// Hidden keys starting with a dot are templates, not jobs
+	return value.Kind == yaml.MappingNode && !slices.Contains(gitlabKeywords, name) && name != "" && name[0] != '.'
+}
+
+// generateBuildkite builds a pipeline upload document. The template must render a step or a list of steps.
+func generateBuildkite(tmpl *template.Template, jobs []Job) ([]byte, error) {
+	steps := &yaml.Node{Kind: yaml.SequenceNode}
+	stepKeys := make(map[string][]string) // key: slug, value: keys of the rendered steps
+
+	for _, job := range jobs {
+		node, err := render(tmpl, job)
+		if err != nil {
+			return nil, err
+		}
+
+		var rendered []*yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			rendered = []*yaml.Node{node}
+		case yaml.SequenceNode:
+			rendered = node.Content
+		default:
+			return nil, fmt.Errorf("job template for %s must render a step or a list of steps", job.Path)
+		}
+
+		var dependsOn []string
+		for _, dependency := range job.DependsOn {
+			dependsOn = append(dependsOn, stepKeys[dependency]...)
+		}
+		for i, step := range rendered {
+			// Steps such as "wait" are plain strings and cannot carry a key
+			if step.Kind != yaml.MappingNode {
+				steps.Content = append(steps.Content, step)
+				continue
+			}
+
+			key, exists := lookup(step, "key")
+			if !exists {
+				value := job.Slug
+				if len(rendered) > 1 {
+					value += "-" + strconv.Itoa(i+1)
+				}
+				key = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+				step.Content = append(step.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "key"}, key)
+			}
+			appendToSequence(step, "depends_on", dependsOn)
+			stepKeys[job.Slug] = append(stepKeys[job.Slug], key.Value)
+			steps.Content = append(steps.Content, step)
+		}
+	}
+
+	document := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "steps"},
+		steps,
+	}}
+	return encode(document)
+}
+
+// render executes the template for the job and parses the result as YAML
+func render(tmpl *template.Template, job Job) (*yaml.Node, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, job); err != nil {
+		return nil, fmt.Errorf("failed to render job template for %s: %w", job.Path, err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(buf.Bytes(), &document); err != nil {
+		return nil, fmt.Errorf("job template for %s rendered invalid YAML: %w", job.Path, err)
+	}
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("job template for %s rendered an empty document", job.Path)
+	}
+	return document.Content[0], nil
+}
+
+// lookup returns the value of the key in a mapping node
+func lookup(mapping *yaml.Node, key string) (*yaml.Node, bool) {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// appendToSequence appends values missing from the sequence under the key of a mapping node,
+// creating the sequence or converting a single value to a sequence as needed
+func appendToSequence(mapping *yaml.Node, key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+
+	sequence, exists := lookup(mapping, key)
+	switch {
+	case !exists:
+		sequence = &yaml.Node{Kind: yaml.SequenceNode}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, sequence)
+	case sequence.Kind == yaml.ScalarNode:
+		*sequence = yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: sequence.Value}}}
+	case sequence.Kind != yaml.SequenceNode:
+		return
+	}
+
+	for _, value := range values {
+		exists := slices.ContainsFunc(sequence.Content, func(item *yaml.Node) bool {
+			return item.Kind == yaml.ScalarNode && item.Value == value
+		})
+		if !exists {
+			sequence.Content = append(sequence.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+		}
+	}
+}
+
+// encode writes a YAML document with two-space indentation
+func encode(document *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return nil, fmt.Errorf("failed to encode pipeline: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode pipeline: %w", err)
+	}
+	return buf.Bytes(), nil
+}
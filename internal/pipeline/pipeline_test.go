@@ -0,0 +1,212 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestGenerate_GitLab(t *testing.T) {
+	tmpl := template.Must(template.New("job").Parse(`stages: [plan, apply]
+.terraform:
+  image: hashicorp/terraform
+plan-{{ .Slug }}:
+  extends: .terraform
+  stage: plan
+  script: ["terraform -chdir={{ .Path }} plan"]
+apply-{{ .Slug }}:
+  extends: .terraform
+  stage: apply
+  needs: [plan-{{ .Slug }}]
+  script: ["terraform -chdir={{ .Path }} apply"]
+`))
+
+	tests := []struct {
+		name     string
+		jobs     []Job
+		expected string
+	}{
+		{
+			name: "Dependent jobs",
+			jobs: []Job{
+				{Path: "roots/network", Slug: "roots-network"},
+				{Path: "roots/app", Slug: "roots-app", DependsOn: []string{"roots-network"}},
+			},
+			expected: `stages: [plan, apply]
+.terraform:
+  image: hashicorp/terraform
+plan-roots-network:
+  extends: .terraform
+  stage: plan
+  script: ["terraform -chdir=roots/network plan"]
+apply-roots-network:
+  extends: .terraform
+  stage: apply
+  needs: [plan-roots-network]
+  script: ["terraform -chdir=roots/network apply"]
+plan-roots-app:
+  extends: .terraform
+  stage: plan
+  script: ["terraform -chdir=roots/app plan"]
+  needs:
+    - plan-roots-network
+    - apply-roots-network
+apply-roots-app:
+  extends: .terraform
+  stage: apply
+  needs: [plan-roots-app, plan-roots-network, apply-roots-network]
+  script: ["terraform -chdir=roots/app apply"]
+`,
+		},
+		{
+			name: "No jobs",
+			jobs: []Job{},
+			expected: `no-changes:
+  script:
+    - echo No root modules were updated
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := Generate(FormatGitLab, tmpl, tt.jobs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(document) != tt.expected {
+				t.Errorf("Expected pipeline:\n%s\ngot:\n%s", tt.expected, document)
+			}
+		})
+	}
+}
+
+func TestGenerate_GitLabDuplicateJob(t *testing.T) {
+	tmpl := template.Must(template.New("job").Parse(`plan:
+  script: ["terraform -chdir={{ .Path }} plan"]
+`))
+
+	_, err := Generate(FormatGitLab, tmpl, []Job{{Path: "a", Slug: "a"}, {Path: "b", Slug: "b"}})
+	if err == nil || !strings.Contains(err.Error(), "duplicate job plan") {
+		t.Errorf("Expected duplicate job error, got %v", err)
+	}
+}
+
+func TestGenerate_GitLabGlobalKeywords(t *testing.T) {
+	tmpl := template.Must(template.New("job").Parse(`image:
+  name: hashicorp/terraform
+  entrypoint: [""]
+cache:
+  paths: [.terraform]
+plan-{{ .Slug }}:
+  script: ["terraform -chdir={{ .Path }} plan"]
+`))
+
+	// Mapping-valued global keywords are rendered once and are not given needs
+	document, err := Generate(FormatGitLab, tmpl, []Job{
+		{Path: "roots/network", Slug: "roots-network"},
+		{Path: "roots/app", Slug: "roots-app", DependsOn: []string{"roots-network"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `image:
+  name: hashicorp/terraform
+  entrypoint: [""]
+cache:
+  paths: [.terraform]
+plan-roots-network:
+  script: ["terraform -chdir=roots/network plan"]
+plan-roots-app:
+  script: ["terraform -chdir=roots/app plan"]
+  needs:
+    - plan-roots-network
+`
+	if string(document) != expected {
+		t.Errorf("Expected pipeline:\n%s\ngot:\n%s", expected, document)
+	}
+}
+
+func TestGenerate_Buildkite(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name: "Single step",
+			template: `label: "plan {{ .Path }}"
+command: terraform -chdir={{ .Path }} plan
+`,
+			expected: `steps:
+  - label: "plan roots/network"
+    command: terraform -chdir=roots/network plan
+    key: roots-network
+  - label: "plan roots/app"
+    command: terraform -chdir=roots/app plan
+    key: roots-app
+    depends_on:
+      - roots-network
+`,
+		},
+		{
+			name: "Multiple steps",
+			template: `- key: plan-{{ .Slug }}
+  command: terraform -chdir={{ .Path }} plan
+- wait
+- command: terraform -chdir={{ .Path }} apply
+  depends_on: plan-{{ .Slug }}
+`,
+			expected: `steps:
+  - key: plan-roots-network
+    command: terraform -chdir=roots/network plan
+  - wait
+  - command: terraform -chdir=roots/network apply
+    depends_on: plan-roots-network
+    key: roots-network-3
+  - key: plan-roots-app
+    command: terraform -chdir=roots/app plan
+    depends_on:
+      - plan-roots-network
+      - roots-network-3
+  - wait
+  - command: terraform -chdir=roots/app apply
+    depends_on:
+      - plan-roots-app
+      - plan-roots-network
+      - roots-network-3
+    key: roots-app-3
+`,
+		},
+	}
+
+	jobs := []Job{
+		{Path: "roots/network", Slug: "roots-network"},
+		{Path: "roots/app", Slug: "roots-app", DependsOn: []string{"roots-network"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New("step").Parse(tt.template))
+			document, err := Generate(FormatBuildkite, tmpl, jobs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(document) != tt.expected {
+				t.Errorf("Expected pipeline:\n%s\ngot:\n%s", tt.expected, document)
+			}
+		})
+	}
+}
+
+func TestGenerate_InvalidTemplateOutput(t *testing.T) {
+	tmpl := template.Must(template.New("job").Parse(`just a string`))
+
+	for _, format := range Formats() {
+		if _, err := Generate(format, tmpl, []Job{{Path: "a", Slug: "a"}}); err == nil {
+			t.Errorf("Expected error for %s", format)
+		}
+	}
+	if _, err := Generate("jenkins", tmpl, nil); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
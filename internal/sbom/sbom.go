@@ -0,0 +1,420 @@
+package sbom
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+	"github.com/hurack3034217/tf-mod-watcher/internal/terraform"
+)
+
+// Supported document formats
+const (
+	FormatCycloneDX = "cyclonedx"
+	FormatSPDX      = "spdx"
+)
+
+// Kinds of components
+const (
+	KindRoot         = "root"
+	KindModule       = "module"
+	KindRemoteModule = "remote-module"
+	KindProvider     = "provider"
+)
+
+// toolName identifies the tool that created the documents
+const toolName = "tf-mod-watcher"
+
+// kindOrder lists the components of a document by kind
+var kindOrder = []string{KindRoot, KindModule, KindRemoteModule, KindProvider}
+
+// spdxInvalidChars matches the characters not allowed in SPDX identifiers
+var spdxInvalidChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// Formats returns the supported document formats
+func Formats() []string {
+	return []string{FormatCycloneDX, FormatSPDX}
+}
+
+// Component is a root module, module or provider in the bill of materials
+type Component struct {
+	Ref       string   // Identifier unique within the bill of materials
+	Kind      string   // KindRoot, KindModule, KindRemoteModule or KindProvider
+	Name      string   // Module ID, remote source address without ref, or provider source address
+	Version   string   // Remote module version, or locked provider version if any and constraint otherwise
+	Hashes    []string // Provider checksums from the lock file of the root module
+	DependsOn []string // Refs of the components used, sorted
+}
+
+// SBOM is a bill of materials of the root modules of a module graph
+type SBOM struct {
+	Name       string
+	Created    time.Time   // Creation time of the document, required by SPDX and omitted by CycloneDX if zero
+	Components []Component // Sorted by kind and ref
+}
+
+// Collect builds the bill of materials of the root modules in the graph. Root modules depend on the modules
+// they call and on the providers required by themselves and the local modules they use, which are pinned by
+// the lock file of the root module when present.
+func Collect(graph *modulegraph.Graph, name string) (*SBOM, error) {
+	components := make(map[string]*Component)
+	add := func(component Component) *Component {
+		if existing, ok := components[component.Ref]; ok {
+			return existing
+		}
+		component.DependsOn = make([]string, 0)
+		components[component.Ref] = &component
+		return &component
+	}
+
+	refs := make(map[string]string, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		switch node.Kind {
+		case modulegraph.KindRoot:
+			refs[node.ID] = add(Component{Ref: "root:" + node.ID, Kind: KindRoot, Name: node.ID}).Ref
+		case modulegraph.KindChild:
+			refs[node.ID] = add(Component{Ref: "module:" + node.ID, Kind: KindModule, Name: node.ID}).Ref
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		to, ok := refs[edge.To]
+		if !ok {
+			address, ref := terraform.SplitRef(edge.Source)
+			version := cmp.Or(edge.Version, ref)
+			to = add(Component{Ref: versionedRef("remote:"+address, version), Kind: KindRemoteModule, Name: address, Version: version}).Ref
+		}
+		from := components[refs[edge.From]]
+		from.DependsOn = append(from.DependsOn, to)
+	}
+
+	for _, node := range graph.Nodes {
+		if node.Kind != modulegraph.KindRoot {
+			continue
+		}
+		providers, err := rootProviders(graph, node)
+		if err != nil {
+			return nil, err
+		}
+		root := components[refs[node.ID]]
+		for _, provider := range providers {
+			root.DependsOn = append(root.DependsOn, add(provider).Ref)
+		}
+	}
+
+	sbom := &SBOM{Name: name, Components: make([]Component, 0, len(components))}
+	for _, component := range components {
+		slices.Sort(component.DependsOn)
+		component.DependsOn = slices.Compact(component.DependsOn)
+		sbom.Components = append(sbom.Components, *component)
+	}
+	slices.SortFunc(sbom.Components, func(a, b Component) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(kindOrder, a.Kind), slices.Index(kindOrder, b.Kind)),
+			cmp.Compare(a.Ref, b.Ref),
+		)
+	})
+	return sbom, nil
+}
+
+// rootProviders returns the providers required by the root module and the local modules it uses,
+// with the versions and hashes of its lock file
+func rootProviders(graph *modulegraph.Graph, root modulegraph.Node) ([]Component, error) {
+	dirs := []string{root.Dir}
+	for _, edge := range graph.Reachable(root.ID) {
+		for _, node := range graph.Nodes {
+			if node.ID == edge.To && node.Kind == modulegraph.KindChild && !slices.Contains(dirs, node.Dir) {
+				dirs = append(dirs, node.Dir)
+			}
+		}
+	}
+
+	constraints := make(map[string][]string)
+	for _, dir := range dirs {
+		requirements, err := terraform.FindRequiredProviders(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find required providers of %s: %w", dir, err)
+		}
+		for _, requirement := range requirements {
+			if _, ok := constraints[requirement.Source]; !ok {
+				constraints[requirement.Source] = make([]string, 0)
+			}
+			if requirement.Version != "" && !slices.Contains(constraints[requirement.Source], requirement.Version) {
+				constraints[requirement.Source] = append(constraints[requirement.Source], requirement.Version)
+			}
+		}
+	}
+
+	locked, err := terraform.ReadLockFile(root.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make([]Component, 0, len(constraints))
+	for source, versions := range constraints {
+		provider := Component{Kind: KindProvider, Name: source, Version: strings.Join(versions, ", "), Hashes: make([]string, 0)}
+		if i := slices.IndexFunc(locked, func(p terraform.LockedProvider) bool { return p.Source == source }); i >= 0 {
+			provider.Version = locked[i].Version
+			provider.Hashes = locked[i].Hashes
+		}
+		provider.Ref = versionedRef("provider:"+source, provider.Version)
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// versionedRef appends the version to the ref of a component, if there is one
+func versionedRef(ref, version string) string {
+	if version == "" {
+		return ref
+	}
+	return ref + "@" + version
+}
+
+// Render renders the bill of materials in the given format
+func (s *SBOM) Render(format string) ([]byte, error) {
+	var document any
+	switch format {
+	case FormatCycloneDX:
+		document = s.cycloneDX()
+	case FormatSPDX:
+		var err error
+		document, err = s.spdx()
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported SBOM format: %s", format)
+	}
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SBOM: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// splitHashes splits lock file hashes into the SHA-256 checksums of provider packages and other hashes
+func splitHashes(hashes []string) ([]string, []string) {
+	sha256Hashes := make([]string, 0)
+	others := make([]string, 0)
+	for _, hash := range hashes {
+		if checksum, ok := strings.CutPrefix(hash, "zh:"); ok {
+			sha256Hashes = append(sha256Hashes, checksum)
+		} else {
+			others = append(others, hash)
+		}
+	}
+	return sha256Hashes, others
+}
+
+// cycloneDXDocument is a CycloneDX 1.5 JSON document
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp,omitempty"`
+	Tools     cycloneDXTools     `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Type       string              `json:"type"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	Hashes     []cycloneDXHash     `json:"hashes,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// cycloneDX converts the bill of materials to a CycloneDX document. The document has no serial number,
+// so that it only depends on the modules.
+func (s *SBOM) cycloneDX() cycloneDXDocument {
+	document := cycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Tools:     cycloneDXTools{Components: []cycloneDXComponent{{Type: "application", Name: toolName}}},
+			Component: cycloneDXComponent{BOMRef: "repository", Type: "application", Name: s.Name},
+		},
+		Components:   make([]cycloneDXComponent, 0, len(s.Components)),
+		Dependencies: make([]cycloneDXDependency, 0, len(s.Components)+1),
+	}
+	if !s.Created.IsZero() {
+		document.Metadata.Timestamp = s.Created.UTC().Format(time.RFC3339)
+	}
+
+	roots := make([]string, 0)
+	for _, component := range s.Components {
+		converted := cycloneDXComponent{
+			BOMRef:     component.Ref,
+			Type:       "library",
+			Name:       component.Name,
+			Version:    component.Version,
+			Properties: []cycloneDXProperty{{Name: toolName + ":kind", Value: component.Kind}},
+		}
+		if component.Kind == KindRoot {
+			converted.Type = "application"
+			roots = append(roots, component.Ref)
+		}
+		sha256Hashes, others := splitHashes(component.Hashes)
+		for _, checksum := range sha256Hashes {
+			converted.Hashes = append(converted.Hashes, cycloneDXHash{Alg: "SHA-256", Content: checksum})
+		}
+		for _, hash := range others {
+			converted.Properties = append(converted.Properties, cycloneDXProperty{Name: toolName + ":hash", Value: hash})
+		}
+		document.Components = append(document.Components, converted)
+		document.Dependencies = append(document.Dependencies, cycloneDXDependency{Ref: component.Ref, DependsOn: component.DependsOn})
+	}
+	document.Dependencies = append([]cycloneDXDependency{{Ref: "repository", DependsOn: roots}}, document.Dependencies...)
+	return document
+}
+
+// spdxDocument is an SPDX 2.3 JSON document
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string         `json:"SPDXID"`
+	Name                  string         `json:"name"`
+	VersionInfo           string         `json:"versionInfo,omitempty"`
+	DownloadLocation      string         `json:"downloadLocation"`
+	FilesAnalyzed         bool           `json:"filesAnalyzed"`
+	Checksums             []spdxChecksum `json:"checksums,omitempty"`
+	PrimaryPackagePurpose string         `json:"primaryPackagePurpose"`
+	Comment               string         `json:"comment"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdx converts the bill of materials to an SPDX document. The namespace is derived from the components,
+// so that documents of the same modules share it.
+func (s *SBOM) spdx() (spdxDocument, error) {
+	if s.Created.IsZero() {
+		return spdxDocument{}, fmt.Errorf("SPDX documents require a creation time")
+	}
+
+	ids := make(map[string]string, len(s.Components))
+	used := make(map[string]struct{}, len(s.Components))
+	for _, component := range s.Components {
+		base := "SPDXRef-" + strings.Trim(spdxInvalidChars.ReplaceAllString(component.Ref, "-"), "-")
+		id := base
+		for n := 2; ; n++ {
+			if _, exists := used[id]; !exists {
+				break
+			}
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[id] = struct{}{}
+		ids[component.Ref] = id
+	}
+
+	document := spdxDocument{
+		SPDXVersion: "SPDX-2.3",
+		DataLicense: "CC0-1.0",
+		SPDXID:      "SPDXRef-DOCUMENT",
+		Name:        s.Name,
+		CreationInfo: spdxCreationInfo{
+			Created:  s.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + toolName},
+		},
+		Packages:      make([]spdxPackage, 0, len(s.Components)),
+		Relationships: make([]spdxRelationship, 0),
+	}
+
+	for _, component := range s.Components {
+		pkg := spdxPackage{
+			SPDXID:                ids[component.Ref],
+			Name:                  component.Name,
+			VersionInfo:           component.Version,
+			DownloadLocation:      "NOASSERTION",
+			PrimaryPackagePurpose: "LIBRARY",
+			Comment:               toolName + " kind: " + component.Kind,
+		}
+		if component.Kind == KindRoot {
+			pkg.PrimaryPackagePurpose = "APPLICATION"
+			document.Relationships = append(document.Relationships, spdxRelationship{
+				SPDXElementID: document.SPDXID, RelationshipType: "DESCRIBES", RelatedSPDXElement: pkg.SPDXID,
+			})
+		}
+		sha256Hashes, _ := splitHashes(component.Hashes)
+		for _, checksum := range sha256Hashes {
+			pkg.Checksums = append(pkg.Checksums, spdxChecksum{Algorithm: "SHA256", ChecksumValue: checksum})
+		}
+		document.Packages = append(document.Packages, pkg)
+
+		for _, dependency := range component.DependsOn {
+			related, exists := ids[dependency]
+			if !exists {
+				return spdxDocument{}, fmt.Errorf("component %s depends on unknown component %s", component.Ref, dependency)
+			}
+			document.Relationships = append(document.Relationships, spdxRelationship{
+				SPDXElementID: pkg.SPDXID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: related,
+			})
+		}
+	}
+
+	packages, err := json.Marshal(document.Packages)
+	if err != nil {
+		return spdxDocument{}, fmt.Errorf("failed to marshal SPDX packages: %w", err)
+	}
+	digest := sha256.Sum256(packages)
+	document.DocumentNamespace = "https://spdx.org/spdxdocs/" + toolName + "-" + hex.EncodeToString(digest[:])
+	return document, nil
+}
@@ -0,0 +1,203 @@
+package sbom
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func buildTestSBOM(t *testing.T) *SBOM {
+	t.Helper()
+
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+module "app" {
+  source = "../../modules/app"
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}`,
+		"envs/prod/.terraform.lock.hcl": `provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:abc=",
+    "zh:0123abcd",
+  ]
+}`,
+		"envs/dev/main.tf": `module "app" {
+  source = "../../modules/app"
+}`,
+		"modules/app/main.tf": `terraform {
+  required_providers {
+    random = {
+      source  = "hashicorp/random"
+      version = ">= 3.0"
+    }
+  }
+}
+
+module "bucket" {
+  source = "git::https://example.com/bucket.git?ref=v1.2.0"
+}`,
+	})
+
+	graph, err := modulegraph.Build([]string{filepath.Join(dir, "envs", "prod"), filepath.Join(dir, "envs", "dev")}, dir, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sbom, err := Collect(graph, "infra")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return sbom
+}
+
+func TestCollect(t *testing.T) {
+	sbom := buildTestSBOM(t)
+
+	expected := []Component{
+		{
+			Ref: "root:envs/dev", Kind: KindRoot, Name: "envs/dev",
+			DependsOn: []string{"module:modules/app", "provider:registry.terraform.io/hashicorp/random@>= 3.0"},
+		},
+		{
+			Ref: "root:envs/prod", Kind: KindRoot, Name: "envs/prod",
+			DependsOn: []string{
+				"module:modules/app",
+				"provider:registry.terraform.io/hashicorp/aws@5.31.0",
+				"provider:registry.terraform.io/hashicorp/random@>= 3.0",
+				"remote:terraform-aws-modules/vpc/aws@5.1.0",
+			},
+		},
+		{
+			Ref: "module:modules/app", Kind: KindModule, Name: "modules/app",
+			DependsOn: []string{"remote:git::https://example.com/bucket.git@v1.2.0"},
+		},
+		{
+			Ref: "remote:git::https://example.com/bucket.git@v1.2.0", Kind: KindRemoteModule,
+			Name: "git::https://example.com/bucket.git", Version: "v1.2.0", DependsOn: []string{},
+		},
+		{
+			Ref: "remote:terraform-aws-modules/vpc/aws@5.1.0", Kind: KindRemoteModule,
+			Name: "terraform-aws-modules/vpc/aws", Version: "5.1.0", DependsOn: []string{},
+		},
+		{
+			Ref: "provider:registry.terraform.io/hashicorp/aws@5.31.0", Kind: KindProvider,
+			Name: "registry.terraform.io/hashicorp/aws", Version: "5.31.0",
+			Hashes: []string{"h1:abc=", "zh:0123abcd"}, DependsOn: []string{},
+		},
+		{
+			Ref: "provider:registry.terraform.io/hashicorp/random@>= 3.0", Kind: KindProvider,
+			Name: "registry.terraform.io/hashicorp/random", Version: ">= 3.0",
+			Hashes: []string{}, DependsOn: []string{},
+		},
+	}
+	if !reflect.DeepEqual(sbom.Components, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, sbom.Components)
+	}
+}
+
+func TestRender(t *testing.T) {
+	sbom := buildTestSBOM(t)
+	sbom.Created = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("CycloneDX", func(t *testing.T) {
+		data, err := sbom.Render(FormatCycloneDX)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var document cycloneDXDocument
+		if err := json.Unmarshal(data, &document); err != nil {
+			t.Fatalf("Failed to parse document: %v", err)
+		}
+		if document.BOMFormat != "CycloneDX" || document.Metadata.Timestamp != "2024-01-02T03:04:05Z" {
+			t.Errorf("Unexpected document header: %+v", document)
+		}
+		if len(document.Components) != len(sbom.Components) || len(document.Dependencies) != len(sbom.Components)+1 {
+			t.Fatalf("Expected %d components, got %d", len(sbom.Components), len(document.Components))
+		}
+		expectedRoots := []string{"root:envs/dev", "root:envs/prod"}
+		if !reflect.DeepEqual(document.Dependencies[0].DependsOn, expectedRoots) {
+			t.Errorf("Expected repository to depend on %v, got %v", expectedRoots, document.Dependencies[0].DependsOn)
+		}
+		aws := document.Components[5]
+		expectedHashes := []cycloneDXHash{{Alg: "SHA-256", Content: "0123abcd"}}
+		if !reflect.DeepEqual(aws.Hashes, expectedHashes) {
+			t.Errorf("Expected hashes %v, got %v", expectedHashes, aws.Hashes)
+		}
+		expectedProperties := []cycloneDXProperty{{Name: "tf-mod-watcher:kind", Value: KindProvider}, {Name: "tf-mod-watcher:hash", Value: "h1:abc="}}
+		if !reflect.DeepEqual(aws.Properties, expectedProperties) {
+			t.Errorf("Expected properties %v, got %v", expectedProperties, aws.Properties)
+		}
+	})
+
+	t.Run("SPDX", func(t *testing.T) {
+		data, err := sbom.Render(FormatSPDX)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var document spdxDocument
+		if err := json.Unmarshal(data, &document); err != nil {
+			t.Fatalf("Failed to parse document: %v", err)
+		}
+		if document.SPDXVersion != "SPDX-2.3" || document.CreationInfo.Created != "2024-01-02T03:04:05Z" {
+			t.Errorf("Unexpected document header: %+v", document)
+		}
+		if document.Packages[0].SPDXID != "SPDXRef-root-envs-dev" {
+			t.Errorf("Expected SPDXRef-root-envs-dev, got %s", document.Packages[0].SPDXID)
+		}
+		expected := spdxRelationship{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-root-envs-dev"}
+		if document.Relationships[0] != expected {
+			t.Errorf("Expected %+v, got %+v", expected, document.Relationships[0])
+		}
+
+		again, err := sbom.Render(FormatSPDX)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(again) != string(data) {
+			t.Error("Expected the same document for the same modules")
+		}
+	})
+
+	t.Run("SPDX without creation time", func(t *testing.T) {
+		sbom := buildTestSBOM(t)
+		if _, err := sbom.Render(FormatSPDX); err == nil {
+			t.Error("Expected error but got none")
+		}
+	})
+
+	t.Run("SPDX with unknown dependency", func(t *testing.T) {
+		sbom := buildTestSBOM(t)
+		sbom.Created = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		sbom.Components[0].DependsOn = append(sbom.Components[0].DependsOn, "module:missing")
+		if _, err := sbom.Render(FormatSPDX); err == nil {
+			t.Error("Expected error but got none")
+		}
+	})
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		if _, err := sbom.Render("syft"); err == nil {
+			t.Error("Expected error but got none")
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package shard
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/stack"
+)
+
+// Shard is a set of root modules run by one CI runner
+type Shard struct {
+	Index  int
+	Weight int
+	Roots  []string // Absolute paths of the root modules, sorted
+}
+
+// unit is a set of root modules that must be run in the same shard
+type unit struct {
+	roots  []string
+	weight int
+}
+
+// Partition distributes the given roots over count shards so that the shards have similar total weights.
+// Roots that depend on each other, directly or through roots outside the given set, are kept in the
+// same shard so that each shard can order them on its own. The result only depends on the arguments.
+func Partition(roots []string, weights map[string]int, graph *stack.Graph, count int) ([]Shard, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("invalid shard count %d: must be at least 1", count)
+	}
+
+	units := groupUnits(roots, weights, graph)
+
+	// Assign the heaviest units first, each to the currently lightest shard
+	slices.SortFunc(units, func(a, b unit) int {
+		return cmp.Or(cmp.Compare(b.weight, a.weight), cmp.Compare(a.roots[0], b.roots[0]))
+	})
+
+	shards := make([]Shard, count)
+	for i := range shards {
+		shards[i] = Shard{Index: i, Roots: make([]string, 0)}
+	}
+	for _, u := range units {
+		lightest := 0
+		for i := range shards {
+			if shards[i].Weight < shards[lightest].Weight {
+				lightest = i
+			}
+		}
+		shards[lightest].Weight += u.weight
+		shards[lightest].Roots = append(shards[lightest].Roots, u.roots...)
+	}
+	for i := range shards {
+		slices.Sort(shards[i].Roots)
+	}
+
+	return shards, nil
+}
+
+// groupUnits merges roots connected by dependencies into units. Roots without a weight weigh 1.
+func groupUnits(roots []string, weights map[string]int, graph *stack.Graph) []unit {
+	roots = slices.Clone(roots)
+	slices.Sort(roots)
+	roots = slices.Compact(roots)
+
+	// Union-find over the roots, keyed by the smallest root of each set
+	parent := make(map[string]string, len(roots))
+	for _, root := range roots {
+		parent[root] = root
+	}
+	var find func(root string) string
+	find = func(root string) string {
+		if parent[root] != root {
+			parent[root] = find(parent[root])
+		}
+		return parent[root]
+	}
+	if graph != nil {
+		for _, root := range roots {
+			for _, dependency := range graph.SelectedDependencies(root, roots) {
+				a, b := find(root), find(dependency)
+				if a == b {
+					continue
+				}
+				if b < a {
+					a, b = b, a
+				}
+				parent[b] = a
+			}
+		}
+	}
+
+	units := make([]unit, 0)
+	index := make(map[string]int)
+	for _, root := range roots {
+		weight, ok := weights[root]
+		if !ok {
+			weight = 1
+		}
+
+		representative := find(root)
+		i, exists := index[representative]
+		if !exists {
+			i = len(units)
+			index[representative] = i
+			units = append(units, unit{})
+		}
+		units[i].roots = append(units[i].roots, root)
+		units[i].weight += weight
+	}
+	return units
+}
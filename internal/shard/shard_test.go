@@ -0,0 +1,86 @@
+package shard
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/stack"
+)
+
+func TestPartition(t *testing.T) {
+	roots := []string{"/repo/app", "/repo/db", "/repo/network", "/repo/batch", "/repo/web"}
+	graph, err := stack.NewGraph(append(roots, "/repo/dns"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// app depends on network, web depends on network through dns, which is not selected
+	for _, dependency := range [][2]string{{"/repo/app", "/repo/network"}, {"/repo/web", "/repo/dns"}, {"/repo/dns", "/repo/network"}} {
+		if err := graph.AddDependency(dependency[0], dependency[1]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		weights  map[string]int
+		graph    *stack.Graph
+		count    int
+		expected []Shard
+	}{
+		{
+			name:  "Unweighted without graph",
+			count: 2,
+			expected: []Shard{
+				{Index: 0, Weight: 3, Roots: []string{"/repo/app", "/repo/db", "/repo/web"}},
+				{Index: 1, Weight: 2, Roots: []string{"/repo/batch", "/repo/network"}},
+			},
+		},
+		{
+			name:  "Dependent roots stay together",
+			graph: graph,
+			count: 2,
+			expected: []Shard{
+				{Index: 0, Weight: 3, Roots: []string{"/repo/app", "/repo/network", "/repo/web"}},
+				{Index: 1, Weight: 2, Roots: []string{"/repo/batch", "/repo/db"}},
+			},
+		},
+		{
+			name:    "Weighted",
+			weights: map[string]int{"/repo/app": 2, "/repo/db": 10, "/repo/network": 3, "/repo/batch": 4, "/repo/web": 1},
+			graph:   graph,
+			count:   3,
+			expected: []Shard{
+				{Index: 0, Weight: 10, Roots: []string{"/repo/db"}},
+				{Index: 1, Weight: 6, Roots: []string{"/repo/app", "/repo/network", "/repo/web"}},
+				{Index: 2, Weight: 4, Roots: []string{"/repo/batch"}},
+			},
+		},
+		{
+			name:  "More shards than roots",
+			graph: graph,
+			count: 4,
+			expected: []Shard{
+				{Index: 0, Weight: 3, Roots: []string{"/repo/app", "/repo/network", "/repo/web"}},
+				{Index: 1, Weight: 1, Roots: []string{"/repo/batch"}},
+				{Index: 2, Weight: 1, Roots: []string{"/repo/db"}},
+				{Index: 3, Weight: 0, Roots: []string{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shards, err := Partition(roots, tt.weights, tt.graph, tt.count)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(shards, tt.expected) {
+				t.Errorf("Expected shards %v, got %v", tt.expected, shards)
+			}
+		})
+	}
+
+	if _, err := Partition(roots, nil, nil, 0); err == nil {
+		t.Error("Expected error for shard count 0")
+	}
+}
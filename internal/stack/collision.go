@@ -0,0 +1,52 @@
+package stack
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+)
+
+// StateCollision describes a state location shared by multiple root modules
+type StateCollision struct {
+	Location string   // State location key
+	Roots    []string // Absolute paths of the root modules writing to the location, sorted
+}
+
+// FindStateCollisions returns every state location that more than one root module writes to.
+// Root modules whose state location cannot be determined statically are skipped.
+func FindStateCollisions(rootModuleDirs []string, logger *slog.Logger) ([]StateCollision, error) {
+	writers := make(map[string][]string)
+	for _, dir := range rootModuleDirs {
+		root, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
+		}
+
+		location, ok, err := StateLocation(root)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			logger.Debug("State location of backend cannot be determined statically", "root", root)
+			continue
+		}
+		if !slices.Contains(writers[location], root) {
+			writers[location] = append(writers[location], root)
+		}
+	}
+
+	collisions := make([]StateCollision, 0)
+	for location, roots := range writers {
+		if len(roots) < 2 {
+			continue
+		}
+		slices.Sort(roots)
+		collisions = append(collisions, StateCollision{Location: location, Roots: roots})
+	}
+	slices.SortFunc(collisions, func(a, b StateCollision) int {
+		return slices.Compare(a.Roots, b.Roots)
+	})
+
+	return collisions, nil
+}
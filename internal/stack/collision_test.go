@@ -0,0 +1,66 @@
+package stack
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestFindStateCollisions(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"org-1/dev/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "service/dev.tfstate"
+  }
+}`,
+		"org-2/dev/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "service/dev.tfstate"
+  }
+}`,
+		"org-1/prod/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "service/prod.tfstate"
+  }
+}`,
+		"partial/main.tf": `terraform {
+  backend "s3" {}
+}`,
+		"partial-copy/main.tf": `terraform {
+  backend "s3" {}
+}`,
+		"local/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	roots := []string{
+		filepath.Join(dir, "org-1", "dev"),
+		filepath.Join(dir, "org-2", "dev"),
+		filepath.Join(dir, "org-1", "prod"),
+		filepath.Join(dir, "partial"),
+		filepath.Join(dir, "partial-copy"),
+		filepath.Join(dir, "local"),
+	}
+	collisions, err := FindStateCollisions(roots, getTestLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []StateCollision{
+		{
+			Location: "s3;bucket=tfstate;key=service/dev.tfstate",
+			Roots: []string{
+				filepath.Join(dir, "org-1", "dev"),
+				filepath.Join(dir, "org-2", "dev"),
+			},
+		},
+	}
+	if !reflect.DeepEqual(collisions, expected) {
+		t.Errorf("Expected collisions %v, got %v", expected, collisions)
+	}
+}
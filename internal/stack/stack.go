@@ -0,0 +1,313 @@
+package stack
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/terraform"
+)
+
+// Graph holds the ordering dependencies between root modules.
+// An edge from A to B means that B must be applied before A.
+type Graph struct {
+	roots        map[string]struct{}            // Set of root module absolute paths
+	dependencies map[string]map[string]struct{} // key: root, value: roots it depends on
+}
+
+// NewGraph creates an empty Graph for the given root module directories
+func NewGraph(rootModuleDirs []string) (*Graph, error) {
+	g := &Graph{
+		roots:        make(map[string]struct{}),
+		dependencies: make(map[string]map[string]struct{}),
+	}
+	for _, dir := range rootModuleDirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
+		}
+		g.roots[absDir] = struct{}{}
+	}
+	return g, nil
+}
+
+// Build creates a Graph whose edges are derived from terraform_remote_state data sources.
+// A root depends on another root if it reads the state written by that root's backend.
+// Roots that cannot be parsed are logged and have no dependencies.
+func Build(rootModuleDirs []string, logger *slog.Logger) (*Graph, error) {
+	g, err := NewGraph(rootModuleDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index roots by the state location their backend writes to
+	writers := make(map[string]string)
+	for _, root := range g.Roots() {
+		location, ok, err := StateLocation(root)
+		if err != nil {
+			logger.Warn("Failed to determine state location", "root", root, "error", err)
+			continue
+		}
+		if !ok {
+			logger.Debug("State location of backend cannot be determined statically", "root", root)
+			continue
+		}
+		if other, exists := writers[location]; exists {
+			logger.Warn("Multiple root modules write to the same state", "root", root, "other", other, "state", location)
+			continue
+		}
+		writers[location] = root
+	}
+
+	for _, root := range g.Roots() {
+		remoteStates, err := terraform.FindRemoteStates(root)
+		if err != nil {
+			logger.Warn("Failed to find remote states", "root", root, "error", err)
+			continue
+		}
+
+		for _, remoteState := range remoteStates {
+			location, ok := remoteState.StateLocation(root)
+			if !ok {
+				logger.Debug("State location of remote state cannot be determined statically", "root", root, "remoteState", remoteState.Name)
+				continue
+			}
+			writer, exists := writers[location]
+			if !exists {
+				logger.Debug("No root module writes the remote state", "root", root, "remoteState", remoteState.Name, "state", location)
+				continue
+			}
+			if writer == root {
+				continue
+			}
+
+			logger.Debug("Found remote state dependency", "root", root, "dependsOn", writer, "remoteState", remoteState.Name)
+			if err := g.AddDependency(root, writer); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// StateLocation returns a key identifying the state written by the given root module.
+// It returns false if the location cannot be determined statically.
+func StateLocation(root string) (string, bool, error) {
+	cloud, err := terraform.FindCloud(root)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to find cloud settings of %s: %w", root, err)
+	}
+	if cloud != nil {
+		location, ok := cloud.StateLocation()
+		return location, ok, nil
+	}
+
+	backend, err := terraform.FindBackend(root)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to find backend of %s: %w", root, err)
+	}
+	if backend == nil {
+		// Terraform falls back to the local backend when none is configured
+		backend = &terraform.Backend{Type: "local", Config: map[string]string{}}
+	}
+
+	location, ok := backend.StateLocation(root)
+	return location, ok, nil
+}
+
+// Roots returns the root modules of the graph, sorted
+func (g *Graph) Roots() []string {
+	roots := make([]string, 0, len(g.roots))
+	for root := range g.roots {
+		roots = append(roots, root)
+	}
+	slices.Sort(roots)
+	return roots
+}
+
+// AddDependency records that root must be applied after dependsOn
+func (g *Graph) AddDependency(root, dependsOn string) error {
+	root = filepath.Clean(root)
+	dependsOn = filepath.Clean(dependsOn)
+	if _, exists := g.roots[root]; !exists {
+		return fmt.Errorf("unknown root module: %s", root)
+	}
+	if _, exists := g.roots[dependsOn]; !exists {
+		return fmt.Errorf("unknown root module: %s", dependsOn)
+	}
+	if root == dependsOn {
+		return fmt.Errorf("root module cannot depend on itself: %s", root)
+	}
+
+	if _, exists := g.dependencies[root]; !exists {
+		g.dependencies[root] = make(map[string]struct{})
+	}
+	g.dependencies[root][dependsOn] = struct{}{}
+	return nil
+}
+
+// Dependencies returns the roots the given root directly depends on, sorted
+func (g *Graph) Dependencies(root string) []string {
+	dependencies := make([]string, 0, len(g.dependencies[root]))
+	for dependency := range g.dependencies[root] {
+		dependencies = append(dependencies, dependency)
+	}
+	slices.Sort(dependencies)
+	return dependencies
+}
+
+// Dependents returns every root that transitively depends on any of the given roots, sorted.
+// The given roots themselves are not included unless they depend on each other.
+func (g *Graph) Dependents(roots []string) []string {
+	reverse := make(map[string][]string)
+	for root, dependencies := range g.dependencies {
+		for dependency := range dependencies {
+			reverse[dependency] = append(reverse[dependency], root)
+		}
+	}
+
+	visited := make(map[string]struct{})
+	queue := slices.Clone(roots)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range reverse[current] {
+			if _, seen := visited[dependent]; seen {
+				continue
+			}
+			visited[dependent] = struct{}{}
+			queue = append(queue, dependent)
+		}
+	}
+
+	dependents := make([]string, 0, len(visited))
+	for dependent := range visited {
+		dependents = append(dependents, dependent)
+	}
+	slices.Sort(dependents)
+	return dependents
+}
+
+// SelectedDependencies returns the given roots that root depends on, either directly or through
+// roots that are not part of the given set, sorted. Dependencies of the returned roots are not included.
+func (g *Graph) SelectedDependencies(root string, roots []string) []string {
+	selected := make(map[string]struct{}, len(roots))
+	for _, r := range roots {
+		selected[filepath.Clean(r)] = struct{}{}
+	}
+
+	found := make(map[string]struct{})
+	visited := make(map[string]struct{})
+	var visit func(current string)
+	visit = func(current string) {
+		for _, dependency := range g.Dependencies(current) {
+			if _, seen := visited[dependency]; seen {
+				continue
+			}
+			visited[dependency] = struct{}{}
+			if _, ok := selected[dependency]; ok {
+				found[dependency] = struct{}{}
+				continue
+			}
+			visit(dependency)
+		}
+	}
+	visit(filepath.Clean(root))
+
+	dependencies := make([]string, 0, len(found))
+	for dependency := range found {
+		dependencies = append(dependencies, dependency)
+	}
+	slices.Sort(dependencies)
+	return dependencies
+}
+
+// Waves groups the given roots into topologically ordered waves.
+// Every root in a wave only depends on roots in earlier waves, including dependencies
+// that go through roots which are not part of the given set. Roots in a wave are sorted.
+func (g *Graph) Waves(roots []string) ([][]string, error) {
+	if err := g.checkCycles(); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]struct{}, len(roots))
+	for _, root := range roots {
+		selected[filepath.Clean(root)] = struct{}{}
+	}
+
+	// Compute the wave index of each root as the longest chain of selected ancestors
+	levels := make(map[string]int)
+	var level func(root string) int
+	level = func(root string) int {
+		if l, found := levels[root]; found {
+			return l
+		}
+		l := 0
+		for _, dependency := range g.Dependencies(root) {
+			dependencyLevel := level(dependency)
+			if _, ok := selected[dependency]; ok {
+				dependencyLevel++
+			}
+			l = max(l, dependencyLevel)
+		}
+		levels[root] = l
+		return l
+	}
+
+	waves := make([][]string, 0)
+	for root := range selected {
+		l := level(root)
+		for len(waves) <= l {
+			waves = append(waves, make([]string, 0))
+		}
+		waves[l] = append(waves[l], root)
+	}
+	for _, wave := range waves {
+		slices.Sort(wave)
+	}
+
+	return waves, nil
+}
+
+// checkCycles returns an error describing the first dependency cycle found
+func (g *Graph) checkCycles() error {
+	const (
+		visiting = iota + 1
+		done
+	)
+	state := make(map[string]int)
+	path := make([]string, 0)
+
+	var visit func(root string) error
+	visit = func(root string) error {
+		switch state[root] {
+		case visiting:
+			start := slices.Index(path, root)
+			cycle := append(slices.Clone(path[start:]), root)
+			return fmt.Errorf("dependency cycle detected between root modules: %s", strings.Join(cycle, " -> "))
+		case done:
+			return nil
+		}
+
+		state[root] = visiting
+		path = append(path, root)
+		for _, dependency := range g.Dependencies(root) {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[root] = done
+		return nil
+	}
+
+	for _, root := range g.Roots() {
+		if err := visit(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package stack
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func getTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError, // Only show errors during tests
+	}))
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"network/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"service/main.tf": `data "terraform_remote_state" "network" {
+  backend = "s3"
+  config = {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"local-producer/main.tf": `resource "null_resource" "this" {}`,
+		"local-consumer/main.tf": `data "terraform_remote_state" "producer" {
+  backend = "local"
+  config = {
+    path = "../local-producer/terraform.tfstate"
+  }
+}`,
+		"broken/main.tf": `resource "x" "y" {`,
+		"unrelated/main.tf": `data "terraform_remote_state" "other" {
+  backend = "s3"
+  config = {
+    bucket = "tfstate"
+    key    = "other.tfstate"
+  }
+}`,
+	})
+
+	roots := []string{
+		filepath.Join(dir, "network"),
+		filepath.Join(dir, "service"),
+		filepath.Join(dir, "local-producer"),
+		filepath.Join(dir, "local-consumer"),
+		filepath.Join(dir, "unrelated"),
+		filepath.Join(dir, "broken"),
+	}
+	graph, err := Build(roots, getTestLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string][]string{
+		"network":        {},
+		"service":        {filepath.Join(dir, "network")},
+		"local-producer": {},
+		"local-consumer": {filepath.Join(dir, "local-producer")},
+		"unrelated":      {},
+		"broken":         {},
+	}
+	for name, dependencies := range expected {
+		actual := graph.Dependencies(filepath.Join(dir, name))
+		if !reflect.DeepEqual(actual, dependencies) {
+			t.Errorf("Expected dependencies of %s to be %v, got %v", name, dependencies, actual)
+		}
+	}
+}
+
+func TestAddDependency(t *testing.T) {
+	graph, err := NewGraph([]string{"/repo/a", "/repo/b"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := graph.AddDependency("/repo/a", "/repo/b"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := graph.AddDependency("/repo/a", "/repo/unknown"); err == nil {
+		t.Error("Expected error for unknown root module")
+	}
+	if err := graph.AddDependency("/repo/a", "/repo/a"); err == nil {
+		t.Error("Expected error for self dependency")
+	}
+}
+
+func TestDependents(t *testing.T) {
+	graph, err := NewGraph([]string{"/repo/network", "/repo/database", "/repo/service", "/repo/other"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mustAddDependency(t, graph, "/repo/database", "/repo/network")
+	mustAddDependency(t, graph, "/repo/service", "/repo/database")
+
+	dependents := graph.Dependents([]string{"/repo/network"})
+	expected := []string{"/repo/database", "/repo/service"}
+	if !reflect.DeepEqual(dependents, expected) {
+		t.Errorf("Expected dependents %v, got %v", expected, dependents)
+	}
+}
+
+func TestWaves(t *testing.T) {
+	graph, err := NewGraph([]string{"/repo/network", "/repo/database", "/repo/service", "/repo/other"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mustAddDependency(t, graph, "/repo/database", "/repo/network")
+	mustAddDependency(t, graph, "/repo/service", "/repo/database")
+
+	tests := []struct {
+		name     string
+		roots    []string
+		expected [][]string
+	}{
+		{
+			name:  "All roots",
+			roots: []string{"/repo/service", "/repo/other", "/repo/database", "/repo/network"},
+			expected: [][]string{
+				{"/repo/network", "/repo/other"},
+				{"/repo/database"},
+				{"/repo/service"},
+			},
+		},
+		{
+			name:  "Ordering through an unselected root",
+			roots: []string{"/repo/service", "/repo/network"},
+			expected: [][]string{
+				{"/repo/network"},
+				{"/repo/service"},
+			},
+		},
+		{
+			name:     "No roots",
+			roots:    []string{},
+			expected: [][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waves, err := graph.Waves(tt.roots)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(waves, tt.expected) {
+				t.Errorf("Expected waves %v, got %v", tt.expected, waves)
+			}
+		})
+	}
+}
+
+func TestSelectedDependencies(t *testing.T) {
+	graph, err := NewGraph([]string{"/repo/network", "/repo/database", "/repo/service", "/repo/other"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mustAddDependency(t, graph, "/repo/database", "/repo/network")
+	mustAddDependency(t, graph, "/repo/service", "/repo/database")
+	mustAddDependency(t, graph, "/repo/service", "/repo/other")
+
+	tests := []struct {
+		name     string
+		roots    []string
+		expected []string
+	}{
+		{
+			name:     "Direct dependencies",
+			roots:    []string{"/repo/network", "/repo/database", "/repo/service", "/repo/other"},
+			expected: []string{"/repo/database", "/repo/other"},
+		},
+		{
+			name:     "Through an unselected root",
+			roots:    []string{"/repo/network", "/repo/service"},
+			expected: []string{"/repo/network"},
+		},
+		{
+			name:     "No selected dependencies",
+			roots:    []string{"/repo/service"},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dependencies := graph.SelectedDependencies("/repo/service", tt.roots)
+			if !reflect.DeepEqual(dependencies, tt.expected) {
+				t.Errorf("Expected dependencies %v, got %v", tt.expected, dependencies)
+			}
+		})
+	}
+}
+
+func TestWaves_Cycle(t *testing.T) {
+	graph, err := NewGraph([]string{"/repo/a", "/repo/b"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mustAddDependency(t, graph, "/repo/a", "/repo/b")
+	mustAddDependency(t, graph, "/repo/b", "/repo/a")
+
+	if _, err := graph.Waves([]string{"/repo/a"}); err == nil {
+		t.Error("Expected error for dependency cycle")
+	}
+}
+
+func mustAddDependency(t *testing.T, graph *Graph, root, dependsOn string) {
+	t.Helper()
+
+	if err := graph.AddDependency(root, dependsOn); err != nil {
+		t.Fatalf("Failed to add dependency: %v", err)
+	}
+}
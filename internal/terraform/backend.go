@@ -0,0 +1,471 @@
+package terraform
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Backend describes the backend block declared in a root module's terraform block
+type Backend struct {
+	Type    string            // Backend type, e.g. "s3" or "local"
+	Config  map[string]string // Attributes whose values are literals
+	Unknown []string          // Attributes whose values cannot be evaluated statically, sorted
+}
+
+// Cloud describes the cloud block declared in a root module's terraform block
+type Cloud struct {
+	Organization  string   // Empty if not set literally
+	Hostname      string   // Empty if not set literally
+	Project       string   // Empty if not set literally
+	WorkspaceName string   // Empty if not set literally
+	WorkspaceTags []string // Literal workspace tags
+	Unknown       []string // Attributes whose values cannot be evaluated statically, sorted
+}
+
+// RemoteState describes a terraform_remote_state data source
+type RemoteState struct {
+	Name    string            // Label of the data block
+	Backend string            // Backend type the remote state is read from
+	Config  map[string]string // Literal entries of the config attribute
+}
+
+// FindBackend returns the backend configured in the given module directory.
+// It returns nil if the module does not declare a backend block.
+func FindBackend(moduleDir string) (*Backend, error) {
+	tfFiles, err := findTerraformFiles(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find terraform files in %s: %w", moduleDir, err)
+	}
+
+	var backend *Backend
+	for _, tfFile := range tfFiles {
+		found, err := extractBackend(tfFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", tfFile, err)
+		}
+		if found == nil {
+			continue
+		}
+		if backend != nil {
+			return nil, fmt.Errorf("multiple backend blocks found in %s", moduleDir)
+		}
+		backend = found
+	}
+
+	return backend, nil
+}
+
+// FindCloud returns the HCP Terraform settings configured in the given module directory.
+// It returns nil if the module does not declare a cloud block.
+func FindCloud(moduleDir string) (*Cloud, error) {
+	tfFiles, err := findTerraformFiles(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find terraform files in %s: %w", moduleDir, err)
+	}
+
+	var cloud *Cloud
+	for _, tfFile := range tfFiles {
+		found, err := extractCloud(tfFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", tfFile, err)
+		}
+		if found == nil {
+			continue
+		}
+		if cloud != nil {
+			return nil, fmt.Errorf("multiple cloud blocks found in %s", moduleDir)
+		}
+		cloud = found
+	}
+
+	return cloud, nil
+}
+
+// FindRemoteStates returns all terraform_remote_state data sources declared in the given module directory
+func FindRemoteStates(moduleDir string) ([]RemoteState, error) {
+	tfFiles, err := findTerraformFiles(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find terraform files in %s: %w", moduleDir, err)
+	}
+
+	remoteStates := make([]RemoteState, 0)
+	for _, tfFile := range tfFiles {
+		found, err := extractRemoteStates(tfFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", tfFile, err)
+		}
+		remoteStates = append(remoteStates, found...)
+	}
+
+	return remoteStates, nil
+}
+
+// extractBackend parses a Terraform file and extracts the backend block if present
+func extractBackend(filePath string) (*Backend, error) {
+	blocks, err := extractTerraformSettingsBlocks(filePath, "backend", []string{"type"})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blocks) > 1 {
+		return nil, fmt.Errorf("multiple backend blocks found")
+	}
+
+	for _, backendBlock := range blocks {
+		backend := &Backend{
+			Type:   backendBlock.Labels[0],
+			Config: make(map[string]string),
+		}
+		unknown, err := collectLiterals(backendBlock.Body, "", backend.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract backend attributes: %w", err)
+		}
+		slices.Sort(unknown)
+		backend.Unknown = unknown
+		return backend, nil
+	}
+
+	return nil, nil
+}
+
+// extractCloud parses a Terraform file and extracts the cloud block if present
+func extractCloud(filePath string) (*Cloud, error) {
+	blocks, err := extractTerraformSettingsBlocks(filePath, "cloud", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blocks) > 1 {
+		return nil, fmt.Errorf("multiple cloud blocks found")
+	}
+
+	for _, cloudBlock := range blocks {
+		content, _, diags := cloudBlock.Body.PartialContent(&hcl.BodySchema{
+			Attributes: []hcl.AttributeSchema{
+				{Name: "organization"},
+				{Name: "hostname"},
+			},
+			Blocks: []hcl.BlockHeaderSchema{
+				{Type: "workspaces"},
+			},
+		})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to extract cloud block content: %s", diags.Error())
+		}
+
+		cloud := &Cloud{
+			WorkspaceTags: make([]string, 0),
+			Unknown:       make([]string, 0),
+		}
+		// literal returns the literal value of an attribute and records it as unknown if it is not a literal
+		literal := func(attrs hcl.Attributes, name, prefix string) string {
+			attr, exists := attrs[name]
+			if !exists {
+				return ""
+			}
+			value, ok := literalString(attr.Expr)
+			if !ok {
+				cloud.Unknown = append(cloud.Unknown, prefix+name)
+			}
+			return value
+		}
+		cloud.Organization = literal(content.Attributes, "organization", "")
+		cloud.Hostname = literal(content.Attributes, "hostname", "")
+
+		for _, workspacesBlock := range content.Blocks {
+			workspacesContent, _, diags := workspacesBlock.Body.PartialContent(&hcl.BodySchema{
+				Attributes: []hcl.AttributeSchema{
+					{Name: "name"},
+					{Name: "project"},
+					{Name: "tags"},
+				},
+			})
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("failed to extract workspaces block content: %s", diags.Error())
+			}
+			cloud.WorkspaceName = literal(workspacesContent.Attributes, "name", "workspaces.")
+			cloud.Project = literal(workspacesContent.Attributes, "project", "workspaces.")
+			if attr, exists := workspacesContent.Attributes["tags"]; exists {
+				tags, diags := hcl.ExprList(attr.Expr)
+				if diags.HasErrors() {
+					cloud.Unknown = append(cloud.Unknown, "workspaces.tags")
+				}
+				for _, tag := range tags {
+					if value, ok := literalString(tag); ok {
+						cloud.WorkspaceTags = append(cloud.WorkspaceTags, value)
+					}
+				}
+			}
+		}
+		slices.Sort(cloud.Unknown)
+		return cloud, nil
+	}
+
+	return nil, nil
+}
+
+// extractTerraformSettingsBlocks parses a Terraform file and returns all blocks of the given type
+// nested in its terraform blocks
+func extractTerraformSettingsBlocks(filePath, blockType string, labelNames []string) ([]*hcl.Block, error) {
+	file, err := parseHCLFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "terraform"},
+		},
+	})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to extract content: %s", diags.Error())
+	}
+
+	blocks := make([]*hcl.Block, 0)
+	for _, terraformBlock := range content.Blocks {
+		terraformContent, _, diags := terraformBlock.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{
+				{
+					Type:       blockType,
+					LabelNames: labelNames,
+				},
+			},
+		})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to extract terraform block content: %s", diags.Error())
+		}
+		blocks = append(blocks, terraformContent.Blocks...)
+	}
+
+	return blocks, nil
+}
+
+// collectLiterals stores the literal attribute values of a body into values and returns
+// the names of attributes whose values are not literals.
+// Attributes of nested blocks are stored with the block type as prefix, e.g. "workspaces.name".
+func collectLiterals(body hcl.Body, prefix string, values map[string]string) ([]string, error) {
+	unknown := make([]string, 0)
+
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		attrs, diags := body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("%s", diags.Error())
+		}
+		for name, attr := range attrs {
+			if value, ok := literalString(attr.Expr); ok {
+				values[prefix+name] = value
+			} else {
+				unknown = append(unknown, prefix+name)
+			}
+		}
+		return unknown, nil
+	}
+
+	for name, attr := range syntaxBody.Attributes {
+		if value, ok := literalString(attr.Expr); ok {
+			values[prefix+name] = value
+		} else {
+			unknown = append(unknown, prefix+name)
+		}
+	}
+	for _, block := range syntaxBody.Blocks {
+		nestedUnknown, err := collectLiterals(block.Body, prefix+block.Type+".", values)
+		if err != nil {
+			return nil, err
+		}
+		unknown = append(unknown, nestedUnknown...)
+	}
+	return unknown, nil
+}
+
+// extractRemoteStates parses a Terraform file and extracts all terraform_remote_state data sources
+func extractRemoteStates(filePath string) ([]RemoteState, error) {
+	file, err := parseHCLFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{
+				Type:       "data",
+				LabelNames: []string{"type", "name"},
+			},
+		},
+	})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to extract content: %s", diags.Error())
+	}
+
+	remoteStates := make([]RemoteState, 0)
+	for _, block := range content.Blocks {
+		if block.Labels[0] != "terraform_remote_state" {
+			continue
+		}
+
+		attrs, diags := block.Body.JustAttributes()
+		if diags.HasErrors() {
+			// Skip blocks that we can't parse (e.g. blocks with nested blocks)
+			continue
+		}
+
+		backendAttr, exists := attrs["backend"]
+		if !exists {
+			continue
+		}
+		backendType, ok := literalString(backendAttr.Expr)
+		if !ok {
+			// Skip if the backend type is not a literal
+			continue
+		}
+
+		remoteState := RemoteState{
+			Name:    block.Labels[1],
+			Backend: backendType,
+			Config:  make(map[string]string),
+		}
+		if configAttr, exists := attrs["config"]; exists {
+			collectObjectLiterals(configAttr.Expr, "", remoteState.Config)
+		}
+		remoteStates = append(remoteStates, remoteState)
+	}
+
+	return remoteStates, nil
+}
+
+// collectObjectLiterals stores the literal entries of an object expression into values.
+// Entries of nested objects are stored with the parent key as prefix, e.g. "workspaces.name".
+func collectObjectLiterals(expr hcl.Expression, prefix string, values map[string]string) {
+	pairs, diags := hcl.ExprMap(expr)
+	if diags.HasErrors() {
+		return
+	}
+
+	for _, pair := range pairs {
+		key, ok := literalString(pair.Key)
+		if !ok {
+			continue
+		}
+		if value, ok := literalString(pair.Value); ok {
+			values[prefix+key] = value
+			continue
+		}
+		collectObjectLiterals(pair.Value, prefix+key+".", values)
+	}
+}
+
+// literalString evaluates an expression without any variables and returns its value as a string.
+// It returns false if the expression is not a literal primitive value.
+func literalString(expr hcl.Expression) (string, bool) {
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() || val.IsNull() || !val.IsWhollyKnown() {
+		return "", false
+	}
+	if !val.Type().IsPrimitiveType() {
+		return "", false
+	}
+
+	strVal, err := convert.Convert(val, cty.String)
+	if err != nil {
+		return "", false
+	}
+
+	return strVal.AsString(), true
+}
+
+// stateIdentityAttributes lists, per backend type, the attributes that identify a state file.
+// An empty default means the attribute must be set explicitly.
+var stateIdentityAttributes = map[string]map[string]string{
+	"local":      {"path": "terraform.tfstate"},
+	"s3":         {"bucket": "", "key": ""},
+	"gcs":        {"bucket": "", "prefix": "default"},
+	"azurerm":    {"storage_account_name": "", "container_name": "", "key": ""},
+	"consul":     {"path": ""},
+	"http":       {"address": ""},
+	"pg":         {"conn_str": "", "schema_name": "terraform_remote_state"},
+	"kubernetes": {"secret_suffix": "", "namespace": "default"},
+	"cos":        {"bucket": "", "prefix": "env:", "key": "terraform.tfstate"},
+	"remote":     {"hostname": "app.terraform.io", "organization": "", "workspaces.name": ""},
+	"oss":        {"bucket": "", "prefix": "env:", "key": "terraform.tfstate"},
+}
+
+// MissingAttributes returns the attributes identifying the state that are neither set
+// nor have a default value, which usually means they are supplied with -backend-config.
+// The result is sorted.
+func (b *Backend) MissingAttributes() []string {
+	missing := make([]string, 0)
+	for name, defaultValue := range stateIdentityAttributes[b.Type] {
+		if _, exists := b.Config[name]; exists || defaultValue != "" || slices.Contains(b.Unknown, name) {
+			continue
+		}
+		missing = append(missing, name)
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// StateLocation returns a key identifying the state file written by the backend.
+// It returns false if the location cannot be determined statically.
+func (b *Backend) StateLocation(moduleDir string) (string, bool) {
+	return stateLocation(b.Type, b.Config, moduleDir)
+}
+
+// StateLocation returns a key identifying the state of the HCP Terraform workspace.
+// It returns false if the workspace is selected by tags or not set literally.
+func (c *Cloud) StateLocation() (string, bool) {
+	// The cloud block shares its state storage with the remote backend
+	config := map[string]string{
+		"organization":    c.Organization,
+		"workspaces.name": c.WorkspaceName,
+	}
+	if c.Hostname != "" {
+		config["hostname"] = c.Hostname
+	}
+	return stateLocation("remote", config, "")
+}
+
+// StateLocation returns a key identifying the state file read by the remote state data source.
+// It returns false if the location cannot be determined statically.
+func (r RemoteState) StateLocation(moduleDir string) (string, bool) {
+	return stateLocation(r.Backend, r.Config, moduleDir)
+}
+
+// stateLocation builds a state location key from a backend type and its configuration
+func stateLocation(backendType string, config map[string]string, moduleDir string) (string, bool) {
+	attributes, known := stateIdentityAttributes[backendType]
+	if !known {
+		return "", false
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	parts := []string{backendType}
+	for _, name := range names {
+		value, exists := config[name]
+		if !exists {
+			value = attributes[name]
+		}
+		if value == "" {
+			return "", false
+		}
+		// Local state paths are relative to the module directory
+		if backendType == "local" && !filepath.IsAbs(value) {
+			value = resolveModulePath(moduleDir, value)
+		}
+		parts = append(parts, name+"="+value)
+	}
+
+	return strings.Join(parts, ";"), true
+}
@@ -0,0 +1,362 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestFindBackend(t *testing.T) {
+	tests := []struct {
+		name            string
+		files           map[string]string
+		expectedType    string
+		expectedConfig  map[string]string
+		expectedUnknown []string
+		expectedMissing []string
+		expectNil       bool
+		shouldError     bool
+	}{
+		{
+			name: "S3 backend with literal values",
+			files: map[string]string{
+				"backend.tf": `terraform {
+  backend "s3" {
+    bucket  = "tfstate"
+    key     = "network/terraform.tfstate"
+    encrypt = true
+    region  = var.region
+  }
+}`,
+			},
+			expectedType: "s3",
+			expectedConfig: map[string]string{
+				"bucket":  "tfstate",
+				"key":     "network/terraform.tfstate",
+				"encrypt": "true",
+			},
+			expectedUnknown: []string{"region"},
+			expectedMissing: []string{},
+		},
+		{
+			name: "Partial S3 backend",
+			files: map[string]string{
+				"backend.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+  }
+}`,
+			},
+			expectedType:    "s3",
+			expectedConfig:  map[string]string{"bucket": "tfstate"},
+			expectedUnknown: []string{},
+			expectedMissing: []string{"key"},
+		},
+		{
+			name: "No backend",
+			files: map[string]string{
+				"main.tf": `terraform {
+  required_version = ">= 1.0"
+}`,
+			},
+			expectNil: true,
+		},
+		{
+			name: "Multiple backends",
+			files: map[string]string{
+				"a.tf": `terraform {
+  backend "local" {}
+}`,
+				"b.tf": `terraform {
+  backend "s3" {}
+}`,
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			testutil.WriteFiles(t, dir, tt.files)
+
+			backend, err := FindBackend(dir)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tt.expectNil {
+				if backend != nil {
+					t.Errorf("Expected no backend, got %+v", backend)
+				}
+				return
+			}
+			if backend == nil {
+				t.Fatal("Expected backend, got nil")
+			}
+			if backend.Type != tt.expectedType {
+				t.Errorf("Expected backend type %s, got %s", tt.expectedType, backend.Type)
+			}
+			if len(backend.Config) != len(tt.expectedConfig) {
+				t.Errorf("Expected config %v, got %v", tt.expectedConfig, backend.Config)
+			}
+			for key, value := range tt.expectedConfig {
+				if backend.Config[key] != value {
+					t.Errorf("Expected config %s=%s, got %s", key, value, backend.Config[key])
+				}
+			}
+			if !reflect.DeepEqual(backend.Unknown, tt.expectedUnknown) {
+				t.Errorf("Expected unknown attributes %v, got %v", tt.expectedUnknown, backend.Unknown)
+			}
+			if missing := backend.MissingAttributes(); !reflect.DeepEqual(missing, tt.expectedMissing) {
+				t.Errorf("Expected missing attributes %v, got %v", tt.expectedMissing, missing)
+			}
+		})
+	}
+}
+
+func TestFindRemoteStates(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"data.tf": `data "terraform_remote_state" "network" {
+  backend = "s3"
+  config = {
+    bucket = "tfstate"
+    key    = "network/terraform.tfstate"
+    region = var.region
+  }
+}
+
+data "aws_caller_identity" "current" {}
+
+data "terraform_remote_state" "dynamic" {
+  backend = var.backend
+}`,
+	})
+
+	remoteStates, err := FindRemoteStates(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(remoteStates) != 1 {
+		t.Fatalf("Expected 1 remote state, got %d: %v", len(remoteStates), remoteStates)
+	}
+	remoteState := remoteStates[0]
+	if remoteState.Name != "network" || remoteState.Backend != "s3" {
+		t.Errorf("Unexpected remote state: %+v", remoteState)
+	}
+	if len(remoteState.Config) != 2 || remoteState.Config["key"] != "network/terraform.tfstate" {
+		t.Errorf("Unexpected remote state config: %v", remoteState.Config)
+	}
+}
+
+func TestStateLocation(t *testing.T) {
+	tests := []struct {
+		name        string
+		backendType string
+		config      map[string]string
+		moduleDir   string
+		expected    string
+		expectedOK  bool
+	}{
+		{
+			name:        "S3 backend",
+			backendType: "s3",
+			config:      map[string]string{"bucket": "tfstate", "key": "a.tfstate", "region": "us-east-1"},
+			moduleDir:   "/repo/network",
+			expected:    "s3;bucket=tfstate;key=a.tfstate",
+			expectedOK:  true,
+		},
+		{
+			name:        "S3 backend with partial configuration",
+			backendType: "s3",
+			config:      map[string]string{"bucket": "tfstate"},
+			moduleDir:   "/repo/network",
+			expectedOK:  false,
+		},
+		{
+			name:        "Default local backend",
+			backendType: "local",
+			config:      map[string]string{},
+			moduleDir:   "/repo/network",
+			expected:    "local;path=/repo/network/terraform.tfstate",
+			expectedOK:  true,
+		},
+		{
+			name:        "Relative local path",
+			backendType: "local",
+			config:      map[string]string{"path": "../network/terraform.tfstate"},
+			moduleDir:   "/repo/service",
+			expected:    "local;path=/repo/network/terraform.tfstate",
+			expectedOK:  true,
+		},
+		{
+			name:        "Unknown backend",
+			backendType: "custom",
+			config:      map[string]string{"path": "x"},
+			moduleDir:   "/repo/network",
+			expectedOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, ok := stateLocation(tt.backendType, tt.config, tt.moduleDir)
+			if ok != tt.expectedOK {
+				t.Fatalf("Expected ok=%v, got %v (%s)", tt.expectedOK, ok, location)
+			}
+			if location != tt.expected {
+				t.Errorf("Expected location %s, got %s", tt.expected, location)
+			}
+		})
+	}
+}
+
+func TestFindBackend_NestedBlocks(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"backend.tf": `terraform {
+  backend "remote" {
+    organization = "example"
+    workspaces {
+      name = "network"
+    }
+  }
+}`,
+	})
+
+	backend, err := FindBackend(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if backend == nil {
+		t.Fatal("Expected backend, got nil")
+	}
+	if backend.Config["workspaces.name"] != "network" {
+		t.Errorf("Expected nested workspace name, got %v", backend.Config)
+	}
+
+	location, ok := backend.StateLocation(dir)
+	if !ok {
+		t.Fatal("Expected state location to be determined")
+	}
+	if location != "remote;hostname=app.terraform.io;organization=example;workspaces.name=network" {
+		t.Errorf("Unexpected state location: %s", location)
+	}
+}
+
+func TestFindCloud(t *testing.T) {
+	tests := []struct {
+		name             string
+		files            map[string]string
+		expected         *Cloud
+		expectedLocation string
+		expectedOK       bool
+	}{
+		{
+			name: "Cloud block with workspace name",
+			files: map[string]string{
+				"main.tf": `terraform {
+  cloud {
+    organization = "example"
+    workspaces {
+      name    = "network"
+      project = "infra"
+    }
+  }
+}`,
+			},
+			expected: &Cloud{
+				Organization:  "example",
+				Project:       "infra",
+				WorkspaceName: "network",
+				WorkspaceTags: []string{},
+				Unknown:       []string{},
+			},
+			expectedLocation: "remote;hostname=app.terraform.io;organization=example;workspaces.name=network",
+			expectedOK:       true,
+		},
+		{
+			name: "Cloud block with workspace tags",
+			files: map[string]string{
+				"main.tf": `terraform {
+  cloud {
+    hostname     = "tfe.example.com"
+    organization = "example"
+    workspaces {
+      tags = ["network", "prod"]
+    }
+  }
+}`,
+			},
+			expected: &Cloud{
+				Organization:  "example",
+				Hostname:      "tfe.example.com",
+				WorkspaceTags: []string{"network", "prod"},
+				Unknown:       []string{},
+			},
+			expectedOK: false,
+		},
+		{
+			name: "Cloud block with non-literal values",
+			files: map[string]string{
+				"main.tf": `terraform {
+  cloud {
+    organization = var.organization
+    workspaces {
+      name = "network-${var.env}"
+    }
+  }
+}`,
+			},
+			expected: &Cloud{
+				WorkspaceTags: []string{},
+				Unknown:       []string{"organization", "workspaces.name"},
+			},
+			expectedOK: false,
+		},
+		{
+			name: "No cloud block",
+			files: map[string]string{
+				"main.tf": `terraform {
+  backend "local" {}
+}`,
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			testutil.WriteFiles(t, dir, tt.files)
+
+			cloud, err := FindCloud(dir)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(cloud, tt.expected) {
+				t.Fatalf("Expected cloud %+v, got %+v", tt.expected, cloud)
+			}
+			if cloud == nil {
+				return
+			}
+
+			location, ok := cloud.StateLocation()
+			if ok != tt.expectedOK {
+				t.Fatalf("Expected ok=%v, got %v (%s)", tt.expectedOK, ok, location)
+			}
+			if location != tt.expectedLocation {
+				t.Errorf("Expected location %s, got %s", tt.expectedLocation, location)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package terraform
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// fileFunctions lists the functions whose first argument is the path of a file read by the module
+var fileFunctions = []string{
+	"file", "filebase64", "filebase64sha256", "filebase64sha512", "fileexists",
+	"filemd5", "filesha1", "filesha256", "filesha512", "templatefile",
+}
+
+// FindReferencedFiles returns the absolute paths of the files the module in the given directory
+// reads with functions such as file and templatefile, sorted.
+// Only paths that are literals or start with path.module are resolved. Literal paths are
+// resolved relative to the module directory, as Terraform does for root modules.
+func FindReferencedFiles(moduleDir string) ([]string, error) {
+	tfFiles, err := findTerraformFiles(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find terraform files in %s: %w", moduleDir, err)
+	}
+
+	absModuleDir, err := filepath.Abs(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", moduleDir, err)
+	}
+
+	files := make([]string, 0)
+	for _, tfFile := range tfFiles {
+		file, err := parseHCLFile(tfFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", tfFile, err)
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			call, ok := node.(*hclsyntax.FunctionCallExpr)
+			if !ok || !slices.Contains(fileFunctions, call.Name) || len(call.Args) == 0 {
+				return nil
+			}
+			if path, ok := modulePath(call.Args[0]); ok {
+				files = append(files, filepath.Join(absModuleDir, filepath.FromSlash(path)))
+			}
+			return nil
+		})
+	}
+
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// modulePath returns the path relative to the module directory given by a literal
+// or by a template starting with path.module, e.g. "${path.module}/templates/user_data.sh"
+func modulePath(expr hclsyntax.Expression) (string, bool) {
+	parts := []hclsyntax.Expression{expr}
+	if template, ok := expr.(*hclsyntax.TemplateExpr); ok {
+		parts = template.Parts
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+
+	fromModule := false
+	if traversal, ok := parts[0].(*hclsyntax.ScopeTraversalExpr); ok {
+		if !isPathModule(traversal.Traversal) {
+			return "", false
+		}
+		fromModule = true
+		parts = parts[1:]
+	}
+
+	var path strings.Builder
+	for _, part := range parts {
+		literal, ok := part.(*hclsyntax.LiteralValueExpr)
+		if !ok || literal.Val.Type() != cty.String || literal.Val.IsNull() {
+			return "", false
+		}
+		path.WriteString(literal.Val.AsString())
+	}
+
+	relPath := path.String()
+	if fromModule {
+		relPath = strings.TrimPrefix(relPath, "/")
+	}
+	if relPath == "" || filepath.IsAbs(relPath) {
+		return "", false
+	}
+	return relPath, true
+}
+
+// isPathModule reports whether the traversal is path.module
+func isPathModule(traversal hcl.Traversal) bool {
+	if len(traversal) != 2 || traversal.RootName() != "path" {
+		return false
+	}
+	attr, ok := traversal[1].(hcl.TraverseAttr)
+	return ok && attr.Name == "module"
+}
@@ -0,0 +1,43 @@
+package terraform
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestFindReferencedFiles(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"main.tf": `resource "aws_instance" "this" {
+  user_data = templatefile("${path.module}/templates/user_data.sh", {
+    name = var.name
+  })
+  tags = {
+    policy = file("policy.json")
+    hash   = filesha256("${path.module}/templates/user_data.sh")
+  }
+}`,
+		"locals.tf": `locals {
+  config  = jsondecode(file("${path.module}/config/${var.env}.json"))
+  root    = file("${path.root}/shared.txt")
+  outside = file("/etc/hosts")
+  other   = lower("${path.module}/not-a-file")
+}`,
+	})
+
+	files, err := FindReferencedFiles(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "policy.json"),
+		filepath.Join(dir, "templates", "user_data.sh"),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected referenced files %v, got %v", expected, files)
+	}
+}
@@ -0,0 +1,112 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// ModuleCall is a module block of a module
+type ModuleCall struct {
+	Name    string // Label of the module block
+	Source  string // Source as written in the module block, empty if Dynamic
+	Dynamic bool   // Source is an expression that cannot be evaluated statically
+	Version string // Version constraint of a registry module, empty if not set
+	File    string // Path of the file containing the source attribute
+	Line    int    // Line of the source attribute
+}
+
+// FindModuleCalls returns the module blocks with a source attribute in the given module directory,
+// in file order. Unlike FindChildModules, remote and non-literal sources are included.
+func FindModuleCalls(moduleDir string) ([]ModuleCall, error) {
+	tfFiles, err := findTerraformFiles(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find terraform files in %s: %w", moduleDir, err)
+	}
+
+	calls := make([]ModuleCall, 0)
+	for _, tfFile := range tfFiles {
+		file, err := parseHCLFile(tfFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", tfFile, err)
+		}
+
+		content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{{Type: "module", LabelNames: []string{"name"}}},
+		})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to extract content of %s: %s", tfFile, diags.Error())
+		}
+
+		for _, block := range content.Blocks {
+			attrs, diags := block.Body.JustAttributes()
+			if diags.HasErrors() {
+				continue
+			}
+			sourceAttr, exists := attrs["source"]
+			if !exists {
+				continue
+			}
+			source, ok := literalString(sourceAttr.Expr)
+			var version string
+			if versionAttr, exists := attrs["version"]; exists {
+				version, _ = literalString(versionAttr.Expr)
+			}
+			calls = append(calls, ModuleCall{
+				Name:    block.Labels[0],
+				Source:  source,
+				Dynamic: !ok,
+				Version: version,
+				File:    tfFile,
+				Line:    sourceAttr.Range.Start.Line,
+			})
+		}
+	}
+
+	return calls, nil
+}
+
+// IsLocalSource reports whether the module source is a local path, which Terraform
+// recognizes by a leading ./ or ../
+func IsLocalSource(source string) bool {
+	for _, prefix := range []string{"./", "../", `.\`, `..\`} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitRef splits a remote module source into the source without its ref query parameter
+// and the ref, e.g. "git::https://example.com/vpc.git?ref=v1.2.0" into "git::https://example.com/vpc.git"
+// and "v1.2.0". Other query parameters are kept.
+func SplitRef(source string) (string, string) {
+	address, query, found := strings.Cut(source, "?")
+	if !found {
+		return source, ""
+	}
+
+	var ref string
+	kept := make([]string, 0)
+	for _, param := range strings.Split(query, "&") {
+		if value, isRef := strings.CutPrefix(param, "ref="); isRef {
+			ref = value
+			continue
+		}
+		kept = append(kept, param)
+	}
+	if len(kept) > 0 {
+		address += "?" + strings.Join(kept, "&")
+	}
+	return address, ref
+}
+
+// HasTerraformFiles reports whether the directory contains .tf files
+func HasTerraformFiles(dir string) (bool, error) {
+	tfFiles, err := findTerraformFiles(dir)
+	if err != nil {
+		return false, err
+	}
+	return len(tfFiles) > 0, nil
+}
@@ -0,0 +1,90 @@
+package terraform
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestFindModuleCalls(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"main.tf": `module "network" {
+  source = "../modules/network"
+}
+module "consul" {
+  source  = "hashicorp/consul/aws"
+  version = "0.1.0"
+}
+module "dynamic" {
+  source = var.source
+}`,
+		"vpc.tf": `module "vpc" {
+  source = "git::https://example.com/vpc.git?ref=v1.2.0"
+}`,
+	})
+
+	calls, err := FindModuleCalls(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mainFile := filepath.Join(dir, "main.tf")
+	expected := []ModuleCall{
+		{Name: "network", Source: "../modules/network", File: mainFile, Line: 2},
+		{Name: "consul", Source: "hashicorp/consul/aws", Version: "0.1.0", File: mainFile, Line: 5},
+		{Name: "dynamic", Dynamic: true, File: mainFile, Line: 9},
+		{Name: "vpc", Source: "git::https://example.com/vpc.git?ref=v1.2.0", File: filepath.Join(dir, "vpc.tf"), Line: 2},
+	}
+	if !slices.Equal(calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+
+	if _, err := FindModuleCalls(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+}
+
+func TestIsLocalSource(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected bool
+	}{
+		{source: "./modules/network", expected: true},
+		{source: "../modules/network", expected: true},
+		{source: "hashicorp/consul/aws", expected: false},
+		{source: "git::https://example.com/vpc.git", expected: false},
+		{source: "modules/network", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if got := IsLocalSource(tt.source); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSplitRef(t *testing.T) {
+	tests := []struct {
+		source          string
+		expectedAddress string
+		expectedRef     string
+	}{
+		{source: "git::https://example.com/vpc.git?ref=v1.2.0", expectedAddress: "git::https://example.com/vpc.git", expectedRef: "v1.2.0"},
+		{source: "git::https://example.com/modules.git//vpc?ref=v1.2.0&depth=1", expectedAddress: "git::https://example.com/modules.git//vpc?depth=1", expectedRef: "v1.2.0"},
+		{source: "github.com/example/vpc?depth=1", expectedAddress: "github.com/example/vpc?depth=1", expectedRef: ""},
+		{source: "hashicorp/consul/aws", expectedAddress: "hashicorp/consul/aws", expectedRef: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			address, ref := SplitRef(tt.source)
+			if address != tt.expectedAddress || ref != tt.expectedRef {
+				t.Errorf("Expected (%q, %q), got (%q, %q)", tt.expectedAddress, tt.expectedRef, address, ref)
+			}
+		})
+	}
+}
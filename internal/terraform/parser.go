@@ -71,11 +71,9 @@ func findTerraformFiles(dir string) ([]string, error) {
 
 // extractModuleSources parses a Terraform file and extracts all module sources
 func extractModuleSources(filePath string) ([]string, error) {
-	parser := hclparse.NewParser()
-
-	file, diags := parser.ParseHCLFile(filePath)
-	if diags.HasErrors() {
-		return nil, fmt.Errorf("failed to parse HCL file: %s", diags.Error())
+	file, err := parseHCLFile(filePath)
+	if err != nil {
+		return nil, err
 	}
 
 	sources := make([]string, 0)
@@ -118,6 +116,18 @@ func extractModuleSources(filePath string) ([]string, error) {
 	return sources, nil
 }
 
+// parseHCLFile parses a single HCL file
+func parseHCLFile(filePath string) (*hcl.File, error) {
+	parser := hclparse.NewParser()
+
+	file, diags := parser.ParseHCLFile(filePath)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse HCL file: %s", diags.Error())
+	}
+
+	return file, nil
+}
+
 // resolveModulePath resolves a relative module source path
 func resolveModulePath(moduleDir, source string) string {
 	// Join the module directory with the source path
@@ -0,0 +1,191 @@
+package terraform
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// LockFileName is the dependency lock file Terraform writes next to the root module configuration
+const LockFileName = ".terraform.lock.hcl"
+
+// defaultProviderNamespace is the registry and namespace of providers whose source is omitted or incomplete
+const defaultProviderNamespace = "registry.terraform.io/hashicorp/"
+
+// ProviderRequirement is an entry of the required_providers block of a module
+type ProviderRequirement struct {
+	Name    string // Local name of the provider, e.g. "aws"
+	Source  string // Fully qualified source address, e.g. "registry.terraform.io/hashicorp/aws"
+	Version string // Version constraint, empty if not set literally
+}
+
+// LockedProvider is a provider block of a dependency lock file
+type LockedProvider struct {
+	Source      string   // Fully qualified source address
+	Version     string   // Selected version
+	Constraints string   // Version constraints the version was selected for
+	Hashes      []string // Package checksums, e.g. "h1:..." or "zh:..."
+}
+
+// FindRequiredProviders returns the providers required by the module in the given directory, sorted by local name.
+// Constraints declared for the same provider in several files are combined.
+func FindRequiredProviders(moduleDir string) ([]ProviderRequirement, error) {
+	tfFiles, err := findTerraformFiles(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find terraform files in %s: %w", moduleDir, err)
+	}
+
+	requirements := make([]ProviderRequirement, 0)
+	for _, tfFile := range tfFiles {
+		found, err := extractRequiredProviders(tfFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", tfFile, err)
+		}
+		for _, requirement := range found {
+			i := slices.IndexFunc(requirements, func(r ProviderRequirement) bool { return r.Name == requirement.Name })
+			if i < 0 {
+				requirements = append(requirements, requirement)
+				continue
+			}
+			if requirement.Version != "" && requirements[i].Version != requirement.Version {
+				requirements[i].Version = strings.Trim(requirements[i].Version+", "+requirement.Version, ", ")
+			}
+		}
+	}
+
+	slices.SortFunc(requirements, func(a, b ProviderRequirement) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return requirements, nil
+}
+
+// extractRequiredProviders parses a Terraform file and extracts the entries of its required_providers blocks.
+// Both the object form and the legacy form with a version constraint string are supported.
+func extractRequiredProviders(filePath string) ([]ProviderRequirement, error) {
+	blocks, err := extractTerraformSettingsBlocks(filePath, "required_providers", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := make([]ProviderRequirement, 0)
+	for _, block := range blocks {
+		attrs, diags := block.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to extract required_providers: %s", diags.Error())
+		}
+
+		for name, attr := range attrs {
+			requirement := ProviderRequirement{Name: name}
+			if version, ok := literalString(attr.Expr); ok {
+				requirement.Version = version
+			} else if pairs, diags := hcl.ExprMap(attr.Expr); !diags.HasErrors() {
+				for _, pair := range pairs {
+					key, ok := literalString(pair.Key)
+					if !ok {
+						continue
+					}
+					switch key {
+					case "source":
+						requirement.Source, _ = literalString(pair.Value)
+					case "version":
+						requirement.Version, _ = literalString(pair.Value)
+					}
+				}
+			}
+			requirement.Source = normalizeProviderSource(cmp.Or(requirement.Source, name))
+			requirements = append(requirements, requirement)
+		}
+	}
+
+	slices.SortFunc(requirements, func(a, b ProviderRequirement) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return requirements, nil
+}
+
+// normalizeProviderSource completes a provider source address with the default registry and namespace
+func normalizeProviderSource(source string) string {
+	source = strings.ToLower(source)
+	switch strings.Count(source, "/") {
+	case 0:
+		return defaultProviderNamespace + source
+	case 1:
+		return "registry.terraform.io/" + source
+	default:
+		return source
+	}
+}
+
+// ReadLockFile returns the providers locked by the dependency lock file in the given directory, sorted by source.
+// It returns nil if the directory has no lock file.
+func ReadLockFile(moduleDir string) ([]LockedProvider, error) {
+	lockFile := filepath.Join(moduleDir, LockFileName)
+	if _, err := os.Stat(lockFile); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to stat %s: %w", lockFile, err)
+	}
+
+	file, err := parseHCLFile(lockFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", lockFile, err)
+	}
+
+	content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{
+				Type:       "provider",
+				LabelNames: []string{"source"},
+			},
+		},
+	})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to extract content of %s: %s", lockFile, diags.Error())
+	}
+
+	providers := make([]LockedProvider, 0, len(content.Blocks))
+	for _, block := range content.Blocks {
+		providerContent, _, diags := block.Body.PartialContent(&hcl.BodySchema{
+			Attributes: []hcl.AttributeSchema{
+				{Name: "version"},
+				{Name: "constraints"},
+				{Name: "hashes"},
+			},
+		})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to extract provider %s of %s: %s", block.Labels[0], lockFile, diags.Error())
+		}
+
+		provider := LockedProvider{Source: normalizeProviderSource(block.Labels[0]), Hashes: make([]string, 0)}
+		if attr, ok := providerContent.Attributes["version"]; ok {
+			provider.Version, _ = literalString(attr.Expr)
+		}
+		if attr, ok := providerContent.Attributes["constraints"]; ok {
+			provider.Constraints, _ = literalString(attr.Expr)
+		}
+		if attr, ok := providerContent.Attributes["hashes"]; ok {
+			hashes, diags := hcl.ExprList(attr.Expr)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("failed to extract hashes of provider %s of %s: %s", block.Labels[0], lockFile, diags.Error())
+			}
+			for _, hash := range hashes {
+				if value, ok := literalString(hash); ok {
+					provider.Hashes = append(provider.Hashes, value)
+				}
+			}
+		}
+		providers = append(providers, provider)
+	}
+
+	slices.SortFunc(providers, func(a, b LockedProvider) int {
+		return cmp.Compare(a.Source, b.Source)
+	})
+	return providers, nil
+}
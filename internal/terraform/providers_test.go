@@ -0,0 +1,106 @@
+package terraform
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestFindRequiredProviders(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"versions.tf": `terraform {
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      version               = ">= 5.0"
+      configuration_aliases = [aws.us_east_1]
+    }
+    random = "~> 3.0"
+    github = {
+      source = "integrations/github"
+    }
+  }
+}`,
+		"override.tf": `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "< 6.0"
+    }
+  }
+}`,
+	})
+
+	requirements, err := FindRequiredProviders(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []ProviderRequirement{
+		{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "< 6.0, >= 5.0"},
+		{Name: "github", Source: "registry.terraform.io/integrations/github"},
+		{Name: "random", Source: "registry.terraform.io/hashicorp/random", Version: "~> 3.0"},
+	}
+	if !reflect.DeepEqual(requirements, expected) {
+		t.Errorf("Expected %v, got %v", expected, requirements)
+	}
+}
+
+func TestReadLockFile(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"locked/.terraform.lock.hcl": `# This file is maintained automatically by "terraform init".
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+  hashes = [
+    "h1:abc=",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = ">= 5.0, < 6.0"
+  hashes = [
+    "h1:def=",
+    "zh:0123abcd",
+  ]
+}
+`,
+		"unlocked/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	tests := []struct {
+		name     string
+		dir      string
+		expected []LockedProvider
+	}{
+		{
+			name: "Lock file",
+			dir:  "locked",
+			expected: []LockedProvider{
+				{Source: "registry.terraform.io/hashicorp/aws", Version: "5.31.0", Constraints: ">= 5.0, < 6.0", Hashes: []string{"h1:def=", "zh:0123abcd"}},
+				{Source: "registry.terraform.io/hashicorp/random", Version: "3.6.0", Hashes: []string{"h1:abc="}},
+			},
+		},
+		{
+			name:     "No lock file",
+			dir:      "unlocked",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers, err := ReadLockFile(filepath.Join(dir, tt.dir))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(providers, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, providers)
+			}
+		})
+	}
+}
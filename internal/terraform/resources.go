@@ -0,0 +1,36 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// CountResources returns the number of resource blocks declared in the given module directory.
+// Resources of child modules are not included.
+func CountResources(moduleDir string) (int, error) {
+	tfFiles, err := findTerraformFiles(moduleDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find terraform files in %s: %w", moduleDir, err)
+	}
+
+	count := 0
+	for _, tfFile := range tfFiles {
+		file, err := parseHCLFile(tfFile)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse %s: %w", tfFile, err)
+		}
+
+		content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{
+				{Type: "resource", LabelNames: []string{"type", "name"}},
+			},
+		})
+		if diags.HasErrors() {
+			return 0, fmt.Errorf("failed to extract content of %s: %s", tfFile, diags.Error())
+		}
+		count += len(content.Blocks)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,35 @@
+package terraform
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestCountResources(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"main.tf": `resource "aws_vpc" "this" {}
+resource "aws_subnet" "public" {}
+data "aws_ami" "this" {}
+module "child" {
+  source = "./child"
+}`,
+		"iam.tf":           `resource "aws_iam_role" "this" {}`,
+		"child/main.tf":    `resource "aws_instance" "this" {}`,
+		"terraform.tfvars": `name = "app"`,
+	})
+
+	count, err := CountResources(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 resources, got %d", count)
+	}
+
+	if _, err := CountResources(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+}
@@ -0,0 +1,199 @@
+package terraform
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// CLI tools a version constraint can apply to
+const (
+	ToolTerraform = "terraform"
+	ToolOpenTofu  = "opentofu"
+)
+
+// versionFileNames lists the files that pin the CLI version for the directory they are in
+// and all of its subdirectories. Each is read by a different version manager (tfenv, tofuenv, asdf),
+// so they are resolved independently and the order only decides between the managers.
+var versionFileNames = []string{".terraform-version", ".opentofu-version", ".tool-versions"}
+
+// versionFileTools lists the tools each version file can pin, in order of precedence
+var versionFileTools = map[string][]string{
+	".terraform-version": {ToolTerraform},
+	".opentofu-version":  {ToolOpenTofu},
+	".tool-versions":     {ToolTerraform, ToolOpenTofu},
+}
+
+// Version is a Terraform or OpenTofu version constraint that applies to a module
+type Version struct {
+	Tool       string // ToolTerraform or ToolOpenTofu, empty if the constraint applies to both
+	Constraint string // Constraint string as written in the source
+	Source     string // Absolute path of the file declaring the constraint
+}
+
+// IsVersionFile reports whether the file at the given path can pin the CLI version
+func IsVersionFile(path string) bool {
+	return slices.Contains(versionFileNames, filepath.Base(path))
+}
+
+// VersionFileTools returns the tools the version file at the given path can pin
+func VersionFileTools(path string) []string {
+	return slices.Clone(versionFileTools[filepath.Base(path)])
+}
+
+// ResolveVersion resolves the CLI version of the module in the given directory.
+// For each version file and tool it can pin, the nearest file pinning the tool is searched
+// in VersionFileDirs. The first one found in order of versionFileNames and then of the tools wins.
+// If there is none, the required_version setting of the module is used.
+// It returns nil if no version is configured.
+func ResolveVersion(moduleDir, rootDir string) (*Version, error) {
+	dirs, err := VersionFileDirs(moduleDir, rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range versionFileNames {
+		for _, tool := range versionFileTools[name] {
+			version, err := FindNearestVersionFile(dirs, name, tool)
+			if err != nil {
+				return nil, err
+			}
+			if version != nil {
+				return version, nil
+			}
+		}
+	}
+
+	return FindRequiredVersion(dirs[0])
+}
+
+// VersionFileDirs returns the absolute paths of the directories whose version files apply to the module,
+// from the module directory up to rootDir. If the module is not inside rootDir, they go up to the filesystem root.
+func VersionFileDirs(moduleDir, rootDir string) ([]string, error) {
+	absModuleDir, err := filepath.Abs(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", moduleDir, err)
+	}
+	absRootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", rootDir, err)
+	}
+
+	dirs := make([]string, 0)
+	for dir := absModuleDir; ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == absRootDir || dir == filepath.Dir(dir) {
+			return dirs, nil
+		}
+	}
+}
+
+// FindNearestVersionFile returns the version of the tool pinned by the first version file with the given name
+// in the directories, or nil if none of them pins a version of the tool
+func FindNearestVersionFile(dirs []string, name, tool string) (*Version, error) {
+	for _, dir := range dirs {
+		version, err := ReadVersionFile(filepath.Join(dir, name), tool)
+		if err != nil {
+			return nil, err
+		}
+		if version != nil {
+			return version, nil
+		}
+	}
+	return nil, nil
+}
+
+// VersionFileNames returns the names of all version files
+func VersionFileNames() []string {
+	return slices.Clone(versionFileNames)
+}
+
+// ReadVersionFile reads a version file and returns the version of the tool it pins.
+// It returns nil if the file does not exist or does not pin a version of the tool.
+func ReadVersionFile(path, tool string) (*Version, error) {
+	name := filepath.Base(path)
+	if !slices.Contains(versionFileTools[name], tool) {
+		if IsVersionFile(path) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unsupported version file: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version file %s: %w", path, err)
+	}
+
+	if name != ".tool-versions" {
+		constraint := strings.TrimSpace(string(data))
+		if constraint == "" {
+			return nil, nil
+		}
+		return &Version{Tool: tool, Constraint: constraint, Source: path}, nil
+	}
+
+	// Each line is "<tool> <version> [<fallback version>...]", comments start with #
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == tool {
+			return &Version{Tool: tool, Constraint: strings.Join(fields[1:], " "), Source: path}, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindRequiredVersion returns the required_version setting of the module in the given directory.
+// It returns nil if the module does not set required_version.
+func FindRequiredVersion(moduleDir string) (*Version, error) {
+	tfFiles, err := findTerraformFiles(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find terraform files in %s: %w", moduleDir, err)
+	}
+
+	for _, tfFile := range tfFiles {
+		file, err := parseHCLFile(tfFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", tfFile, err)
+		}
+
+		content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{
+				{Type: "terraform"},
+			},
+		})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to extract content of %s: %s", tfFile, diags.Error())
+		}
+
+		for _, terraformBlock := range content.Blocks {
+			terraformContent, _, diags := terraformBlock.Body.PartialContent(&hcl.BodySchema{
+				Attributes: []hcl.AttributeSchema{
+					{Name: "required_version"},
+				},
+			})
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("failed to extract terraform block content of %s: %s", tfFile, diags.Error())
+			}
+
+			if attr, exists := terraformContent.Attributes["required_version"]; exists {
+				if constraint, ok := literalString(attr.Expr); ok {
+					// Both Terraform and OpenTofu honor required_version
+					return &Version{Constraint: constraint, Source: tfFile}, nil
+				}
+			}
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,134 @@
+package terraform
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestResolveVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		moduleDir string
+		rootDir   string // Relative to the temporary directory, which is used if empty
+		expected  *Version
+	}{
+		{
+			name: "Version file in module directory",
+			files: map[string]string{
+				"live/prod/main.tf":            `terraform { required_version = ">= 1.5" }`,
+				"live/prod/.terraform-version": "1.6.2\n",
+				"live/.terraform-version":      "1.5.7\n",
+				"live/prod/.tool-versions":     "opentofu 1.7.0\n",
+			},
+			moduleDir: "live/prod",
+			expected:  &Version{Tool: ToolTerraform, Constraint: "1.6.2", Source: "live/prod/.terraform-version"},
+		},
+		{
+			name: "Version file inherited from parent directory",
+			files: map[string]string{
+				"live/prod/main.tf":       `terraform { required_version = ">= 1.5" }`,
+				"live/.terraform-version": "1.5.7\n",
+			},
+			moduleDir: "live/prod",
+			expected:  &Version{Tool: ToolTerraform, Constraint: "1.5.7", Source: "live/.terraform-version"},
+		},
+		{
+			name: "Tool versions file",
+			files: map[string]string{
+				"live/prod/main.tf": `resource "null_resource" "this" {}`,
+				"live/.tool-versions": `# runtimes
+nodejs 20.0.0
+opentofu 1.7.0 1.6.0
+`,
+			},
+			moduleDir: "live/prod",
+			expected:  &Version{Tool: ToolOpenTofu, Constraint: "1.7.0 1.6.0", Source: "live/.tool-versions"},
+		},
+		{
+			name: "Tool versions file does not override terraform version file of parent directory",
+			files: map[string]string{
+				"live/prod/main.tf":        `resource "null_resource" "this" {}`,
+				"live/prod/.tool-versions": "terraform 1.6.0\n",
+				"live/.terraform-version":  "1.5.7\n",
+			},
+			moduleDir: "live/prod",
+			expected:  &Version{Tool: ToolTerraform, Constraint: "1.5.7", Source: "live/.terraform-version"},
+		},
+		{
+			name: "Version file above root directory",
+			files: map[string]string{
+				"live/prod/main.tf":       `terraform { required_version = ">= 1.5" }`,
+				"live/.terraform-version": "1.5.7\n",
+			},
+			moduleDir: "live/prod",
+			rootDir:   "live/prod",
+			expected:  &Version{Constraint: ">= 1.5", Source: "live/prod/main.tf"},
+		},
+		{
+			name: "Tool versions file without terraform",
+			files: map[string]string{
+				"live/prod/main.tf":   `terraform { required_version = "~> 1.5.0" }`,
+				"live/.tool-versions": "nodejs 20.0.0\n",
+			},
+			moduleDir: "live/prod",
+			expected:  &Version{Constraint: "~> 1.5.0", Source: "live/prod/main.tf"},
+		},
+		{
+			name: "No version",
+			files: map[string]string{
+				"live/prod/main.tf": `resource "null_resource" "this" {}`,
+			},
+			moduleDir: "live/prod",
+			expected:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			testutil.WriteFiles(t, dir, tt.files)
+
+			version, err := ResolveVersion(filepath.Join(dir, tt.moduleDir), filepath.Join(dir, tt.rootDir))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var expected *Version
+			if tt.expected != nil {
+				expected = &Version{
+					Tool:       tt.expected.Tool,
+					Constraint: tt.expected.Constraint,
+					Source:     filepath.Join(dir, tt.expected.Source),
+				}
+			}
+			if !reflect.DeepEqual(version, expected) {
+				t.Errorf("Expected %+v, got %+v", expected, version)
+			}
+		})
+	}
+}
+
+func TestIsVersionFile(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "/repo/.terraform-version", expected: true},
+		{path: "/repo/live/.opentofu-version", expected: true},
+		{path: "/repo/.tool-versions", expected: true},
+		{path: "/repo/main.tf", expected: false},
+		{path: "/repo/.terraform-version.bak", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if result := IsVersionFile(tt.path); result != tt.expected {
+				t.Errorf("IsVersionFile(%q) = %v, want %v", tt.path, result, tt.expected)
+			}
+		})
+	}
+}
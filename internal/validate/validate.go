@@ -0,0 +1,109 @@
+package validate
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/terraform"
+)
+
+// Kinds of problems with module sources
+const (
+	KindNonLiteral        = "non-literal"        // Source is not a literal string
+	KindAbsolute          = "absolute"           // Source is an absolute path
+	KindOutsideRepository = "outside-repository" // Local source escapes the repository root
+	KindMissing           = "missing"            // Local source does not exist
+	KindNoTerraformFiles  = "no-terraform-files" // Local source is not a directory with .tf files
+)
+
+// Problem is a module block whose source cannot be followed
+type Problem struct {
+	Kind    string
+	Module  string // Absolute path of the module containing the module block
+	Call    terraform.ModuleCall
+	Message string
+}
+
+// Modules checks the sources of the module blocks in the given module directories.
+// Local sources must stay inside repoRoot. Problems are sorted by file and line.
+// Modules that cannot be parsed are logged and skipped.
+func Modules(moduleDirs []string, repoRoot string, logger *slog.Logger) ([]Problem, error) {
+	absRepoRoot, err := filepath.Abs(repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", repoRoot, err)
+	}
+
+	problems := make([]Problem, 0)
+	for _, moduleDir := range moduleDirs {
+		absModuleDir, err := filepath.Abs(moduleDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for %s: %w", moduleDir, err)
+		}
+		calls, err := terraform.FindModuleCalls(absModuleDir)
+		if err != nil {
+			logger.Warn("Failed to find module calls", "module", absModuleDir, "error", err)
+			continue
+		}
+
+		for _, call := range calls {
+			kind, message, err := checkCall(absModuleDir, call, absRepoRoot)
+			if err != nil {
+				return nil, err
+			}
+			if kind != "" {
+				problems = append(problems, Problem{Kind: kind, Module: absModuleDir, Call: call, Message: message})
+			}
+		}
+	}
+
+	slices.SortFunc(problems, func(a, b Problem) int {
+		return cmp.Or(cmp.Compare(a.Call.File, b.Call.File), cmp.Compare(a.Call.Line, b.Call.Line))
+	})
+	return problems, nil
+}
+
+// checkCall returns the kind of problem with the source of the module block and a message describing it,
+// or an empty kind if the source is valid. Remote sources are not checked.
+func checkCall(moduleDir string, call terraform.ModuleCall, repoRoot string) (string, string, error) {
+	switch {
+	case call.Dynamic:
+		return KindNonLiteral, "source is not a literal string", nil
+	case filepath.IsAbs(call.Source):
+		return KindAbsolute, fmt.Sprintf("source %s is an absolute path", call.Source), nil
+	case !terraform.IsLocalSource(call.Source):
+		return "", "", nil
+	}
+
+	dir := filepath.Join(moduleDir, call.Source)
+	relPath, err := filepath.Rel(repoRoot, dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to compute relative path of %s: %w", dir, err)
+	}
+	if relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return KindOutsideRepository, fmt.Sprintf("source %s is outside the repository root", call.Source), nil
+	}
+
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return KindMissing, fmt.Sprintf("source %s does not exist", call.Source), nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to access %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return KindNoTerraformFiles, fmt.Sprintf("source %s is not a directory", call.Source), nil
+	}
+	hasTerraformFiles, err := terraform.HasTerraformFiles(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to check for Terraform files in %s: %w", dir, err)
+	}
+	if !hasTerraformFiles {
+		return KindNoTerraformFiles, fmt.Sprintf("source %s contains no .tf files", call.Source), nil
+	}
+	return "", "", nil
+}
@@ -0,0 +1,80 @@
+package validate
+
+import (
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	repoRoot := filepath.Join(dir, "repo")
+	testutil.WriteFiles(t, repoRoot, map[string]string{
+		"envs/prod/main.tf": `module "service" {
+  source = "../../modules/service"
+}
+module "typo" {
+  source = "../../modules/servce"
+}
+module "docs" {
+  source = "../../modules/docs"
+}
+module "absolute" {
+  source = "/opt/modules/service"
+}`,
+		"envs/prod/other.tf": `module "dynamic" {
+  source = "../../modules/${var.name}"
+}
+module "outside" {
+  source = "../../../shared"
+}
+module "file" {
+  source = "./other.tf"
+}
+module "remote" {
+  source = "hashicorp/consul/aws"
+}`,
+		"envs/broken/main.tf":     `resource "x" "y" {`,
+		"modules/service/main.tf": `resource "null_resource" "this" {}`,
+		"modules/docs/README.md":  "# docs",
+	})
+	testutil.WriteFiles(t, dir, map[string]string{
+		"shared/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	// Modules that cannot be parsed are skipped
+	moduleDirs := []string{filepath.Join(repoRoot, "envs", "broken"), filepath.Join(repoRoot, "envs", "prod")}
+	problems, err := Modules(moduleDirs, repoRoot, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mainFile := filepath.Join(repoRoot, "envs", "prod", "main.tf")
+	otherFile := filepath.Join(repoRoot, "envs", "prod", "other.tf")
+	expected := []struct {
+		kind    string
+		name    string
+		file    string
+		line    int
+		message string
+	}{
+		{KindMissing, "typo", mainFile, 5, "source ../../modules/servce does not exist"},
+		{KindNoTerraformFiles, "docs", mainFile, 8, "source ../../modules/docs contains no .tf files"},
+		{KindAbsolute, "absolute", mainFile, 11, "source /opt/modules/service is an absolute path"},
+		{KindNonLiteral, "dynamic", otherFile, 2, "source is not a literal string"},
+		{KindOutsideRepository, "outside", otherFile, 5, "source ../../../shared is outside the repository root"},
+		{KindNoTerraformFiles, "file", otherFile, 8, "source ./other.tf is not a directory"},
+	}
+
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %+v", len(expected), len(problems), problems)
+	}
+	for i, want := range expected {
+		got := problems[i]
+		if got.Kind != want.kind || got.Call.Name != want.name || got.Call.File != want.file || got.Call.Line != want.line || got.Message != want.message {
+			t.Errorf("Problem %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package workspace
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// placeholder is replaced by the workspace name in convention patterns
+const placeholder = "{workspace}"
+
+// Convention describes where the per-workspace files of a root module live,
+// e.g. "env/{workspace}.tfvars" relative to the root module directory
+type Convention struct {
+	pattern string
+	matcher *regexp.Regexp
+}
+
+// Target is a root module, optionally deployed to a specific workspace
+type Target struct {
+	Root      string // Absolute path of the root module
+	Workspace string // Empty if the root module has no workspaces
+}
+
+// ParseConvention parses a convention pattern containing exactly one {workspace} placeholder
+func ParseConvention(pattern string) (Convention, error) {
+	if strings.Count(pattern, placeholder) != 1 {
+		return Convention{}, fmt.Errorf("invalid workspace convention %s: must contain %s exactly once", pattern, placeholder)
+	}
+	if filepath.IsAbs(pattern) {
+		return Convention{}, fmt.Errorf("invalid workspace convention %s: must be relative to the root module", pattern)
+	}
+
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	prefix, suffix, _ := strings.Cut(pattern, placeholder)
+	matcher, err := regexp.Compile("^" + regexp.QuoteMeta(prefix) + "([^/]+)" + regexp.QuoteMeta(suffix) + "$")
+	if err != nil {
+		return Convention{}, fmt.Errorf("invalid workspace convention %s: %w", pattern, err)
+	}
+
+	return Convention{pattern: pattern, matcher: matcher}, nil
+}
+
+// String returns the pattern of the convention
+func (c Convention) String() string {
+	return c.pattern
+}
+
+// Match returns the workspace a file belongs to if it follows the convention for the given root module
+func (c Convention) Match(rootDir, file string) (string, bool) {
+	relPath, err := filepath.Rel(rootDir, file)
+	if err != nil {
+		return "", false
+	}
+
+	matches := c.matcher.FindStringSubmatch(filepath.ToSlash(relPath))
+	if matches == nil {
+		return "", false
+	}
+	return matches[1], true
+}
+
+// Workspaces returns the workspaces of the given root module that have a file following the convention, sorted
+func (c Convention) Workspaces(rootDir string) ([]string, error) {
+	glob := filepath.Join(rootDir, filepath.FromSlash(strings.Replace(c.pattern, placeholder, "*", 1)))
+	files, err := filepath.Glob(glob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search workspace files %s: %w", glob, err)
+	}
+
+	workspaces := make([]string, 0, len(files))
+	for _, file := range files {
+		if workspace, ok := c.Match(rootDir, file); ok {
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	slices.Sort(workspaces)
+	return workspaces, nil
+}
+
+// FindWorkspaces returns the workspaces of the given root module over all conventions, sorted
+func FindWorkspaces(rootDir string, conventions []Convention) ([]string, error) {
+	workspaces := make([]string, 0)
+	for _, convention := range conventions {
+		found, err := convention.Workspaces(rootDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, workspace := range found {
+			if !slices.Contains(workspaces, workspace) {
+				workspaces = append(workspaces, workspace)
+			}
+		}
+	}
+	slices.Sort(workspaces)
+	return workspaces, nil
+}
+
+// SplitChanges separates changes to per-workspace files from the other changed files.
+// It returns the changed workspaces per root module and the remaining changed files,
+// which affect all workspaces of the root modules that use them.
+// Root modules are keyed by their absolute paths.
+func SplitChanges(rootDirs []string, changedFiles map[string]struct{}, conventions []Convention) (map[string][]string, map[string]struct{}, error) {
+	changedWorkspaces := make(map[string][]string)
+	remainingFiles := make(map[string]struct{}, len(changedFiles))
+
+	absRootDirs := make([]string, 0, len(rootDirs))
+	for _, rootDir := range rootDirs {
+		absRootDir, err := filepath.Abs(rootDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get absolute path for %s: %w", rootDir, err)
+		}
+		absRootDirs = append(absRootDirs, absRootDir)
+	}
+
+	for changedFile := range changedFiles {
+		matched := false
+		for _, rootDir := range absRootDirs {
+			for _, convention := range conventions {
+				workspace, ok := convention.Match(rootDir, changedFile)
+				if !ok {
+					continue
+				}
+				matched = true
+				if !slices.Contains(changedWorkspaces[rootDir], workspace) {
+					changedWorkspaces[rootDir] = append(changedWorkspaces[rootDir], workspace)
+				}
+			}
+		}
+		if !matched {
+			remainingFiles[changedFile] = struct{}{}
+		}
+	}
+
+	return changedWorkspaces, remainingFiles, nil
+}
+
+// BuildTargets returns the targets to deploy: every workspace of the updated root modules and
+// the changed workspaces of the other root modules. Targets are sorted by root and workspace.
+func BuildTargets(updatedRootDirs []string, changedWorkspaces map[string][]string, conventions []Convention) ([]Target, error) {
+	targets := make([]Target, 0)
+
+	for _, rootDir := range updatedRootDirs {
+		workspaces, err := FindWorkspaces(rootDir, conventions)
+		if err != nil {
+			return nil, err
+		}
+		// A deleted workspace file no longer shows up in the search but is still a change
+		for _, workspace := range changedWorkspaces[rootDir] {
+			if !slices.Contains(workspaces, workspace) {
+				workspaces = append(workspaces, workspace)
+			}
+		}
+
+		if len(workspaces) == 0 {
+			targets = append(targets, Target{Root: rootDir})
+			continue
+		}
+		for _, workspace := range workspaces {
+			targets = append(targets, Target{Root: rootDir, Workspace: workspace})
+		}
+	}
+
+	for rootDir, workspaces := range changedWorkspaces {
+		if slices.Contains(updatedRootDirs, rootDir) {
+			continue
+		}
+		for _, workspace := range workspaces {
+			targets = append(targets, Target{Root: rootDir, Workspace: workspace})
+		}
+	}
+
+	slices.SortFunc(targets, func(a, b Target) int {
+		return cmp.Or(cmp.Compare(a.Root, b.Root), cmp.Compare(a.Workspace, b.Workspace))
+	})
+	return targets, nil
+}
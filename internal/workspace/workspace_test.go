@@ -0,0 +1,148 @@
+package workspace
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestParseConvention(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		expectErr bool
+	}{
+		{name: "Var file in subdirectory", pattern: "env/{workspace}.tfvars"},
+		{name: "Workspace directory", pattern: "workspaces/{workspace}/terraform.tfvars"},
+		{name: "Missing placeholder", pattern: "env/prod.tfvars", expectErr: true},
+		{name: "Duplicate placeholder", pattern: "{workspace}/{workspace}.tfvars", expectErr: true},
+		{name: "Absolute pattern", pattern: "/env/{workspace}.tfvars", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConvention(tt.pattern)
+			if tt.expectErr && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestConvention_Match(t *testing.T) {
+	convention, err := ParseConvention("env/{workspace}.tfvars")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		file              string
+		expectedWorkspace string
+		expectedMatch     bool
+	}{
+		{name: "Var file", file: "/repo/app/env/prod.tfvars", expectedWorkspace: "prod", expectedMatch: true},
+		{name: "Nested directory", file: "/repo/app/env/prod/main.tfvars", expectedMatch: false},
+		{name: "Other extension", file: "/repo/app/env/prod.tfvars.json", expectedMatch: false},
+		{name: "Other root module", file: "/repo/other/env/prod.tfvars", expectedMatch: false},
+		{name: "Configuration file", file: "/repo/app/main.tf", expectedMatch: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace, ok := convention.Match("/repo/app", tt.file)
+			if ok != tt.expectedMatch || workspace != tt.expectedWorkspace {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.expectedWorkspace, tt.expectedMatch, workspace, ok)
+			}
+		})
+	}
+}
+
+func TestFindWorkspaces(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"main.tf":                             `resource "null_resource" "this" {}`,
+		"env/prod.tfvars":                     `size = "large"`,
+		"env/dev.tfvars":                      `size = "small"`,
+		"env/README.md":                       `# Environments`,
+		"workspaces/staging/terraform.tfvars": `size = "medium"`,
+		"workspaces/prod/terraform.tfvars":    `size = "large"`,
+	})
+
+	conventions := make([]Convention, 0)
+	for _, pattern := range []string{"env/{workspace}.tfvars", "workspaces/{workspace}/terraform.tfvars"} {
+		convention, err := ParseConvention(pattern)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		conventions = append(conventions, convention)
+	}
+
+	workspaces, err := FindWorkspaces(dir, conventions)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"dev", "prod", "staging"}
+	if !reflect.DeepEqual(workspaces, expected) {
+		t.Errorf("Expected %v, got %v", expected, workspaces)
+	}
+}
+
+func TestSplitChangesAndBuildTargets(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"app/main.tf":         `resource "null_resource" "this" {}`,
+		"app/env/dev.tfvars":  `size = "small"`,
+		"app/env/prod.tfvars": `size = "large"`,
+		"db/main.tf":          `resource "null_resource" "this" {}`,
+		"db/env/prod.tfvars":  `size = "large"`,
+		"cache/main.tf":       `resource "null_resource" "this" {}`,
+	})
+	app := filepath.Join(dir, "app")
+	db := filepath.Join(dir, "db")
+	cache := filepath.Join(dir, "cache")
+
+	convention, err := ParseConvention("env/{workspace}.tfvars")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conventions := []Convention{convention}
+
+	changedFiles := map[string]struct{}{
+		filepath.Join(app, "main.tf"):            {},
+		filepath.Join(db, "env", "prod.tfvars"):  {},
+		filepath.Join(db, "env", "stale.tfvars"): {},
+	}
+
+	changedWorkspaces, remainingFiles, err := SplitChanges([]string{app, db, cache}, changedFiles, conventions)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changedWorkspaces) != 1 || len(changedWorkspaces[db]) != 2 {
+		t.Errorf("Expected two changed workspaces of db, got %v", changedWorkspaces)
+	}
+	expectedRemaining := map[string]struct{}{filepath.Join(app, "main.tf"): {}}
+	if !reflect.DeepEqual(remainingFiles, expectedRemaining) {
+		t.Errorf("Expected remaining files %v, got %v", expectedRemaining, remainingFiles)
+	}
+
+	targets, err := BuildTargets([]string{app, cache}, changedWorkspaces, conventions)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []Target{
+		{Root: app, Workspace: "dev"},
+		{Root: app, Workspace: "prod"},
+		{Root: cache},
+		{Root: db, Workspace: "prod"},
+		{Root: db, Workspace: "stale"},
+	}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Expected %v, got %v", expected, targets)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 
@@ -10,6 +11,9 @@ import (
 
 func main() {
 	if err := cli.NewApp(os.Stdout).Run(context.Background(), os.Args); err != nil {
+		if errors.Is(err, cli.ErrChanges) {
+			os.Exit(cli.ExitCodeChanges)
+		}
 		slog.Error("CLI execution failed", "error", err)
 		os.Exit(1)
 	}
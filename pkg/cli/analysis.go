@@ -0,0 +1,376 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/analyzer"
+	"github.com/hurack3034217/tf-mod-watcher/internal/attribute"
+	"github.com/hurack3034217/tf-mod-watcher/internal/filekind"
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+	"github.com/hurack3034217/tf-mod-watcher/internal/stack"
+	"github.com/hurack3034217/tf-mod-watcher/internal/workspace"
+)
+
+// analysis holds the findings of an analysis run
+type analysis struct {
+	basePath            string
+	commits             *commitRange        // nil when changed files are given directly
+	searchDirs          []string            // Directories searched for root modules
+	rootModuleDirs      []string            // Discovered root modules
+	changedFiles        map[string]struct{} // All changed files
+	rootChangedFiles    map[string]struct{} // Changed files other than per-workspace files
+	updatedModuleDirs   []string            // Absolute paths of the updated root modules
+	dependentModuleDirs []string            // Absolute paths of the root modules depending on updated ones
+	changedWorkspaces   map[string][]string // Changed workspaces keyed by the absolute path of the root module
+	conventions         []workspace.Convention
+	patterns            []attribute.Pattern // Patterns deriving attributes from root module paths
+	filters             []attribute.Filter
+	targets             []workspace.Target
+	graph               *stack.Graph // nil unless ordering was requested
+	warnings            *warningRecorder
+}
+
+// changeSourceFlags returns the mutually exclusive flags selecting where changed files come from
+func changeSourceFlags() []cli.MutuallyExclusiveFlags {
+	return []cli.MutuallyExclusiveFlags{
+		{
+			Required: false,
+			Flags: [][]cli.Flag{
+				{
+					&cli.StringFlag{
+						Name:  "before-commit",
+						Value: "HEAD^",
+						Usage: "Old commit hash or reference",
+					},
+					&cli.StringFlag{
+						Name:  "after-commit",
+						Value: "HEAD",
+						Usage: "New commit hash or reference",
+					},
+					&cli.StringFlag{
+						Name:  "git-repository-root-path",
+						Usage: "Git repository root path for git operations (default: auto-detected git repository root)",
+					},
+				},
+				{
+					&cli.StringSliceFlag{
+						Name:  "changed-file",
+						Usage: "List of changed file paths (can be specified multiple times)",
+					},
+				},
+			},
+		},
+	}
+}
+
+// analysisFlags returns the flags controlling which root modules and workspaces are reported
+func analysisFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "include-dependents",
+			Usage: "Also report root modules that depend on the state of an updated root module",
+		},
+		&cli.StringSliceFlag{
+			Name:  "stack-dependency",
+			Usage: "Explicit dependency between root modules in the form <root>=<depends-on> (can be specified multiple times)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "workspace-var-file",
+			Usage: "Per-workspace file relative to each root module, e.g. env/{workspace}.tfvars (can be specified multiple times)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "path-pattern",
+			Usage: "Pattern deriving attributes from root module paths, e.g. environments/{org}/{env} or a regular expression starting with ^ (can be specified multiple times)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "Only report root modules with the attribute value in the form <key>=<value> (can be specified multiple times)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "only-kinds",
+			Usage: "Only treat changed files of these kinds as changes (" + strings.Join(filekind.Kinds(), ", ") + "; comma-separated or specified multiple times)",
+		},
+	}
+}
+
+// analyze finds the updated root modules with the changeSourceFlags and analysisFlags of cmd.
+// The dependency graph between root modules is built if ordering is requested or needed for dependents.
+func analyze(cmd *cli.Command, warnings *warningRecorder, ordering bool, logger *slog.Logger) (*analysis, error) {
+	// Parse arguments
+	beforeCommit := cmd.String("before-commit")
+	afterCommit := cmd.String("after-commit")
+	rootModuleDirs := cmd.StringSlice("root-module-dir")
+	gitRepoRootPath := cmd.String("git-repository-root-path")
+	basePath := cmd.String("base-path")
+	changedFiles := cmd.StringSlice("changed-file")
+	includeDependents := cmd.Bool("include-dependents")
+
+	conventions, err := parseWorkspaceConventions(cmd.StringSlice("workspace-var-file"))
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := parsePathPatterns(cmd.StringSlice("path-pattern"))
+	if err != nil {
+		return nil, err
+	}
+	filters, err := parseFilters(cmd.StringSlice("filter"))
+	if err != nil {
+		return nil, err
+	}
+	onlyKinds, err := filekind.ParseKinds(cmd.StringSlice("only-kinds"))
+	if err != nil {
+		return nil, err
+	}
+
+	var changedFilesMap map[string]struct{}
+	var commits *commitRange
+
+	if len(changedFiles) > 0 {
+		// Use provided changed files
+		logger.Info("Using provided changed files", "count", len(changedFiles))
+		changedFilesMap = make(map[string]struct{})
+		for _, filePath := range changedFiles {
+			absPath, err := filepath.Abs(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get absolute path for changed file %s: %w", filePath, err)
+			}
+			changedFilesMap[absPath] = struct{}{}
+		}
+		if basePath == "" {
+			// If base-path is still not set, use current working directory
+			currentDir, err := os.Getwd()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get current working directory: %w", err)
+			}
+			basePath = currentDir
+			logger.Info("Using current working directory as base-path", "basePath", basePath)
+		}
+	} else {
+		// If git-repository-root-path is not specified, find git repository root
+		if gitRepoRootPath == "" {
+			logger.Debug("git-repository-root-path not specified, searching for git repository root")
+			repoRoot, err := findGitRepositoryRoot()
+			if err != nil {
+				return nil, fmt.Errorf("failed to find git repository root: %w (please specify --git-repository-root-path)", err)
+			}
+			gitRepoRootPath = repoRoot
+			logger.Info("Using auto-detected git repository root", "gitRepoRootPath", gitRepoRootPath)
+		}
+		// Search for changed files using git
+		changedFilesMap, err = searchChangedFiles(gitRepoRootPath, beforeCommit, afterCommit, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for changed files: %w", err)
+		}
+		commits, err = resolveCommitRange(gitRepoRootPath, beforeCommit, afterCommit)
+		if err != nil {
+			return nil, err
+		}
+		// If base-path is not specified, use git-repository-root-path
+		if basePath == "" {
+			basePath = gitRepoRootPath
+			logger.Info("Using git-repository-root-path as base-path", "basePath", basePath)
+		}
+	}
+
+	// Validate base-path exists
+	if _, err := os.Stat(basePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("base-path does not exist: %s", basePath)
+	}
+
+	logger.Info("Found changed files", "count", len(changedFilesMap))
+	if len(onlyKinds) > 0 {
+		for changedFile := range changedFilesMap {
+			if !slices.Contains(onlyKinds, filekind.Classify(changedFile)) {
+				delete(changedFilesMap, changedFile)
+			}
+		}
+		logger.Info("Ignoring changed files of other kinds", "kinds", onlyKinds, "count", len(changedFilesMap))
+	}
+	logger.Debug("Changed files", "files", changedFilesMap)
+
+	// changedFiles already contains absolute paths from GetChangedFiles
+	// Find all root modules in the specified directories
+	foundRootModuleDirs, err := discoverRootModules(rootModuleDirs, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.Bool("check-backends") {
+		if err := checkBackendCollisions(foundRootModuleDirs, basePath, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Info("Starting analysis",
+		"before", beforeCommit,
+		"after", afterCommit,
+		"gitRepoRootPath", gitRepoRootPath,
+		"basePath", basePath,
+		"rootModuleDirs", rootModuleDirs,
+		"changedFiles", changedFilesMap,
+	)
+
+	// Broken and unused modules do not fail the analysis, but changes behind them are likely mistakes.
+	// Problems in the module sources are reported by the warnings below, not while building the graph,
+	// and modules that cannot be parsed are reported by the analyzer.
+	moduleGraph, err := modulegraph.Build(foundRootModuleDirs, basePath, slog.New(slog.DiscardHandler))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build module graph: %w", err)
+	}
+	repoRoot := gitRepoRootPath
+	if repoRoot == "" {
+		repoRoot = repositoryRoot(basePath, logger)
+	}
+	if err := warnInvalidSources(moduleGraph, repoRoot, basePath, logger); err != nil {
+		return nil, err
+	}
+	if err := warnUnusedChanges(moduleGraph, changedFilesMap, logger); err != nil {
+		return nil, err
+	}
+
+	// Changes to per-workspace files only affect their own workspace
+	changedWorkspaces, rootChangedFiles, err := workspace.SplitChanges(foundRootModuleDirs, changedFilesMap, conventions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split workspace changes: %w", err)
+	}
+	logger.Debug("Changed workspaces", "workspaces", changedWorkspaces)
+
+	// Analyze root modules
+	logger.Info("Analyzing root modules")
+	updatedModuleDirs, err := analyzer.FindUpdatedRootModules(
+		foundRootModuleDirs,
+		rootChangedFiles,
+		basePath,
+		logger,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze root modules: %w", err)
+	}
+
+	// Build the dependency graph between root modules only when ordering is needed
+	var stackGraph *stack.Graph
+	if includeDependents || ordering {
+		logger.Info("Building dependency graph between root modules")
+		stackGraph, err = buildStackGraph(foundRootModuleDirs, cmd.StringSlice("stack-dependency"), logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build dependency graph: %w", err)
+		}
+	}
+
+	dependentModuleDirs := make([]string, 0)
+	if includeDependents {
+		// A root module with changed workspaces also changes the state its dependents read
+		changedRoots := slices.Clone(updatedModuleDirs)
+		for root := range changedWorkspaces {
+			if !slices.Contains(changedRoots, root) {
+				changedRoots = append(changedRoots, root)
+			}
+		}
+
+		dependents := stackGraph.Dependents(changedRoots)
+		logger.Info("Found dependent root modules", "count", len(dependents))
+		for _, dependent := range dependents {
+			if !slices.Contains(updatedModuleDirs, dependent) {
+				dependentModuleDirs = append(dependentModuleDirs, dependent)
+			}
+		}
+	}
+
+	targets, err := workspace.BuildTargets(slices.Concat(updatedModuleDirs, dependentModuleDirs), changedWorkspaces, conventions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build targets: %w", err)
+	}
+
+	logger.Info("Analysis complete", "updatedModules", len(updatedModuleDirs)+len(dependentModuleDirs), "targets", len(targets))
+
+	a := &analysis{
+		basePath:            basePath,
+		commits:             commits,
+		searchDirs:          rootModuleDirs,
+		rootModuleDirs:      foundRootModuleDirs,
+		changedFiles:        changedFilesMap,
+		rootChangedFiles:    rootChangedFiles,
+		updatedModuleDirs:   updatedModuleDirs,
+		dependentModuleDirs: dependentModuleDirs,
+		changedWorkspaces:   changedWorkspaces,
+		conventions:         conventions,
+		patterns:            patterns,
+		filters:             filters,
+		targets:             targets,
+		graph:               stackGraph,
+		warnings:            warnings,
+	}
+	a.applyFilters()
+	if len(filters) > 0 {
+		logger.Info("Filtered root modules", "filters", cmd.StringSlice("filter"), "targets", len(a.targets))
+	}
+	return a, nil
+}
+
+// collectChains returns, for every updated root module, each path from the root module through
+// its child modules to a changed file, e.g. "envs/prod -> modules/service -> main.tf".
+// Module paths are relative to the base path and files are relative to the module containing them.
+func collectChains(a *analysis, logger *slog.Logger) (map[string][]string, error) {
+	changeAnalyzer, err := analyzer.NewAnalyzer(a.rootChangedFiles, a.basePath, logger)
+	if err != nil {
+		return nil, err
+	}
+	absBasePath, err := filepath.Abs(a.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", a.basePath, err)
+	}
+
+	format := func(chain analyzer.Chain) (string, error) {
+		elements := make([]string, 0, len(chain.Modules)+1)
+		for _, module := range chain.Modules {
+			relPath, err := filepath.Rel(absBasePath, module)
+			if err != nil {
+				return "", fmt.Errorf("failed to compute relative path of %s: %w", module, err)
+			}
+			elements = append(elements, filepath.ToSlash(relPath))
+		}
+		file, err := filepath.Rel(chain.Modules[len(chain.Modules)-1], chain.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to compute relative path of %s: %w", chain.File, err)
+		}
+		return strings.Join(append(elements, filepath.ToSlash(file)), " -> "), nil
+	}
+
+	roots := slices.Clone(a.updatedModuleDirs)
+	for root := range a.changedWorkspaces {
+		if !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+
+	chains := make(map[string][]string, len(roots))
+	for _, root := range roots {
+		rootChains := make([]analyzer.Chain, 0)
+		if slices.Contains(a.updatedModuleDirs, root) {
+			changes, err := changeAnalyzer.CollectChanges(root)
+			if err != nil {
+				return nil, fmt.Errorf("failed to collect changes of %s: %w", root, err)
+			}
+			rootChains = append(rootChains, changes.Chains...)
+		}
+		for _, file := range workspaceFiles(root, a.changedFiles, a.conventions) {
+			rootChains = append(rootChains, analyzer.Chain{Modules: []string{root}, File: file})
+		}
+
+		for _, chain := range rootChains {
+			formatted, err := format(chain)
+			if err != nil {
+				return nil, err
+			}
+			chains[root] = append(chains[root], formatted)
+		}
+	}
+	return chains, nil
+}
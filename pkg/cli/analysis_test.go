@@ -0,0 +1,303 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunAnalysis_Explain(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "service" {
+  source = "../../modules/service"
+}
+module "worker" {
+  source = "../../modules/worker"
+}`,
+		"envs/prod/env/blue.tfvars": `color = "blue"`,
+		"envs/dev/main.tf":          `resource "null_resource" "this" {}`,
+		"modules/service/main.tf": `module "service_2" {
+  source = "./service-2"
+}`,
+		"modules/service/service-2/main.tf": `resource "null_resource" "this" {}`,
+		"modules/worker/main.tf": `module "service_2" {
+  source = "../service/service-2"
+}`,
+	})
+
+	args := []string{
+		os.Args[0],
+		"--root-module-dir", filepath.Join(dir, "envs"),
+		"--base-path", dir,
+		"--changed-file", filepath.Join(dir, "modules", "service", "service-2", "main.tf"),
+		"--changed-file", filepath.Join(dir, "envs", "prod", "env", "blue.tfvars"),
+		"--workspace-var-file", "env/{workspace}.tfvars",
+		"--explain",
+		"--log-level", "error",
+	}
+	expectedChains := []string{
+		"envs/prod -> modules/service -> modules/service/service-2 -> main.tf",
+		"envs/prod -> modules/worker -> modules/service/service-2 -> main.tf",
+		"envs/prod -> env/blue.tfvars",
+	}
+
+	var buf bytes.Buffer
+	if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+		t.Fatalf("NewApp().Run() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"envs/prod -> env/blue.tfvars"`) {
+		t.Errorf("Expected chains without escaped characters, got %s", buf.String())
+	}
+	var entries []rootEntry
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != "envs/prod" || entries[0].Workspace != "blue" {
+		t.Fatalf("Unexpected entries: %s", buf.String())
+	}
+	if !reflect.DeepEqual(entries[0].Chains, expectedChains) {
+		t.Errorf("Expected chains %v, got %v", expectedChains, entries[0].Chains)
+	}
+
+	buf.Reset()
+	if err := NewApp(&buf).Run(context.Background(), append(args, "--output-format", "json-v2")); err != nil {
+		t.Fatalf("NewApp().Run() failed: %v", err)
+	}
+	checkSchema(t, "result-v2.schema.json", buf.Bytes())
+	var result resultV2
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	for _, root := range result.Roots {
+		switch root.Path {
+		case "envs/prod":
+			if !reflect.DeepEqual(root.Chains, expectedChains) {
+				t.Errorf("Expected chains %v, got %v", expectedChains, root.Chains)
+			}
+		default:
+			if len(root.Chains) != 0 {
+				t.Errorf("Expected no chains for %s, got %v", root.Path, root.Chains)
+			}
+		}
+	}
+}
+
+func TestRunAnalysis_UnusedModuleWarning(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "service" {
+  source = "../../modules/service"
+}`,
+		"modules/service/main.tf":     `resource "null_resource" "this" {}`,
+		"modules/service-old/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	args := []string{
+		os.Args[0],
+		"--root-module-dir", filepath.Join(dir, "envs"),
+		"--base-path", dir,
+		"--changed-file", filepath.Join(dir, "modules", "service", "main.tf"),
+		"--changed-file", filepath.Join(dir, "modules", "service-old", "main.tf"),
+		"--output-format", "json-v2",
+		"--log-level", "error",
+	}
+
+	var buf bytes.Buffer
+	if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var result resultV2
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	expected := []warning{{
+		Message: "Changed files are in a module that no root module uses",
+		Attributes: map[string]string{
+			"module": filepath.Join(dir, "modules", "service-old"),
+			"files":  "[" + filepath.Join(dir, "modules", "service-old", "main.tf") + "]",
+		},
+	}}
+	if !reflect.DeepEqual(result.Warnings, expected) {
+		t.Errorf("Expected warnings %+v, got %+v", expected, result.Warnings)
+	}
+}
+
+func TestRunAnalysis_InvalidSourceWarning(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "service" {
+  source = "../../modules/servce"
+}`,
+		"modules/service/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	args := []string{
+		os.Args[0],
+		"--root-module-dir", filepath.Join(dir, "envs"),
+		"--base-path", dir,
+		"--changed-file", filepath.Join(dir, "envs", "prod", "main.tf"),
+		"--output-format", "json-v2",
+		"--log-level", "error",
+	}
+
+	var buf bytes.Buffer
+	if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var result resultV2
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	expected := []warning{{
+		Message: "Invalid module source",
+		Attributes: map[string]string{
+			"file":    "envs/prod/main.tf",
+			"line":    "2",
+			"module":  "service",
+			"kind":    "missing",
+			"problem": "source ../../modules/servce does not exist",
+		},
+	}}
+	if !reflect.DeepEqual(result.Warnings, expected) {
+		t.Errorf("Expected warnings %+v, got %+v", expected, result.Warnings)
+	}
+}
+
+func TestRunAnalysis_UnparsableRoot(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/a/main.tf": `module "m" {
+  source = "../../modules/m"
+}`,
+		"envs/b/main.tf":    `resource "x" "y" {`,
+		"modules/m/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	args := []string{
+		os.Args[0],
+		"--root-module-dir", filepath.Join(dir, "envs"),
+		"--base-path", dir,
+		"--changed-file", filepath.Join(dir, "modules", "m", "main.tf"),
+		"--log-level", "error",
+	}
+
+	// A broken root is logged and does not fail the analysis of the others
+	var buf bytes.Buffer
+	if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `["envs/a"]`; buf.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, buf.String())
+	}
+}
+
+func TestRunAnalysis_ChangeKinds(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "service" {
+  source = "../../modules/service"
+}`,
+		"envs/prod/.terraform.lock.hcl": ``,
+		"envs/prod/terraform.tfvars":    `name = "prod"`,
+		"envs/dev/main.tf":              `resource "null_resource" "this" {}`,
+		"envs/dev/README.md":            `# dev`,
+		"modules/service/main.tf":       `resource "null_resource" "this" {}`,
+		"modules/service/user_data.sh":  `#!/bin/sh`,
+	})
+
+	changedFiles := []string{
+		filepath.Join(dir, "envs", "prod", ".terraform.lock.hcl"),
+		filepath.Join(dir, "envs", "prod", "terraform.tfvars"),
+		filepath.Join(dir, "envs", "dev", "README.md"),
+		filepath.Join(dir, "modules", "service", "user_data.sh"),
+	}
+
+	tests := []struct {
+		name          string
+		onlyKinds     []string
+		expectedKinds map[string][]string
+		expectedError bool
+	}{
+		{
+			name: "All kinds",
+			expectedKinds: map[string][]string{
+				"envs/dev":  {"docs"},
+				"envs/prod": {"vars", "lock", "assets"},
+			},
+		},
+		{
+			name:      "Only some kinds",
+			onlyKinds: []string{"vars,assets"},
+			expectedKinds: map[string][]string{
+				"envs/dev":  {},
+				"envs/prod": {"vars", "assets"},
+			},
+		},
+		{
+			name:      "Only kinds without changes",
+			onlyKinds: []string{"config", "tests"},
+			expectedKinds: map[string][]string{
+				"envs/dev":  {},
+				"envs/prod": {},
+			},
+		},
+		{
+			name:          "Unknown kind",
+			onlyKinds:     []string{"code"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{
+				os.Args[0],
+				"--root-module-dir", filepath.Join(dir, "envs"),
+				"--base-path", dir,
+				"--output-format", "json-v2",
+				"--log-level", "error",
+			}
+			for _, file := range changedFiles {
+				args = append(args, "--changed-file", file)
+			}
+			for _, kinds := range tt.onlyKinds {
+				args = append(args, "--only-kinds", kinds)
+			}
+
+			var buf bytes.Buffer
+			err := NewApp(&buf).Run(context.Background(), args)
+			if tt.expectedError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			checkSchema(t, "result-v2.schema.json", buf.Bytes())
+			var result resultV2
+			if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to parse output: %v", err)
+			}
+			kinds := make(map[string][]string)
+			for _, root := range result.Roots {
+				kinds[root.Path] = root.ChangeKinds
+			}
+			if !reflect.DeepEqual(kinds, tt.expectedKinds) {
+				t.Errorf("Expected change kinds %v, got %v", tt.expectedKinds, kinds)
+			}
+		})
+	}
+}
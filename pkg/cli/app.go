@@ -1,70 +1,118 @@
 package cli
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/urfave/cli/v3"
 
-	"github.com/hurack3034217/tf-mod-watcher/internal/analyzer"
 	gitpkg "github.com/hurack3034217/tf-mod-watcher/internal/git"
+	"github.com/hurack3034217/tf-mod-watcher/internal/shard"
+	"github.com/hurack3034217/tf-mod-watcher/internal/workspace"
 )
 
+const (
+	outputFormatJSON     = "json"
+	outputFormatJSONV2   = "json-v2"
+	outputFormatWaves    = "waves"
+	outputFormatMarkdown = "markdown"
+	outputFormatShards   = "shards"
+)
+
+// ExitCodeChanges is the exit code for ErrChanges, like terraform plan -detailed-exitcode
+const ExitCodeChanges = 2
+
+// ErrChanges is returned with the exit-code flag when the analysis found updated root modules
+var ErrChanges = errors.New("root modules were updated")
+
+// outputFormats lists the supported values of the output-format flag
+var outputFormats = []string{outputFormatJSON, outputFormatJSONV2, outputFormatWaves, outputFormatMarkdown, outputFormatShards}
+
 // NewApp creates and configures the CLI application
 func NewApp(writer io.Writer) *cli.Command {
 	return &cli.Command{
-		Name:  "tf-module-analyzer",
-		Usage: "Analyzes updated Terraform root modules based on git diff",
-		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
-			{
-				Required: false,
-				Flags: [][]cli.Flag{
-					{
-						&cli.StringFlag{
-							Name:  "before-commit",
-							Value: "HEAD^",
-							Usage: "Old commit hash or reference",
-						},
-						&cli.StringFlag{
-							Name:  "after-commit",
-							Value: "HEAD",
-							Usage: "New commit hash or reference",
-						},
-						&cli.StringFlag{
-							Name:  "git-repository-root-path",
-							Usage: "Git repository root path for git operations (default: auto-detected git repository root)",
-						},
-					},
-					{
-						&cli.StringSliceFlag{
-							Name:  "changed-file",
-							Usage: "List of changed file paths (can be specified multiple times)",
-						},
-					},
-				},
-			},
-		},
-		Flags: []cli.Flag{
+		Name:                   "tf-module-analyzer",
+		Usage:                  "Analyzes updated Terraform root modules based on git diff",
+		MutuallyExclusiveFlags: changeSourceFlags(),
+		Flags: slices.Concat([]cli.Flag{
 			&cli.StringSliceFlag{
-				Name:     "root-module-dir",
-				Usage:    "Paths to root module directories (can be specified multiple times)",
-				Required: true,
+				Name:       "root-module-dir",
+				Usage:      "Paths to root module directories (can be specified multiple times)",
+				Required:   true,
+				Persistent: true,
+			},
+			&cli.StringFlag{
+				Name:       "base-path",
+				Usage:      "Base path for relative path calculation in output (default: same as git-repository-root-path)",
+				Persistent: true,
+			},
+			&cli.StringFlag{
+				Name:  "output-format",
+				Value: outputFormatJSON,
+				Usage: "Output format (json, json-v2, waves, markdown, shards)",
+			},
+			&cli.StringFlag{
+				Name:  "output-template",
+				Usage: "Go text/template rendering the json-v2 result instead of JSON, inline or @<path> to read it from a file",
 			},
 			&cli.StringFlag{
-				Name:  "base-path",
-				Usage: "Base path for relative path calculation in output (default: same as git-repository-root-path)",
+				Name:  "output-file",
+				Usage: "Path to write the output to instead of standard output, replaced atomically",
+			},
+			&cli.BoolFlag{
+				Name:  "quiet",
+				Usage: "Do not write the output to standard output",
+			},
+			&cli.BoolFlag{
+				Name:  "exit-code",
+				Usage: fmt.Sprintf("Exit with %d if any root module is updated, 0 if none is and 1 on errors", ExitCodeChanges),
+			},
+			&cli.BoolFlag{
+				Name:  "explain",
+				Usage: "Output every chain from each updated root module through its child modules to a changed file",
+			},
+			&cli.StringSliceFlag{
+				Name:  "group-by",
+				Usage: "Nest the output by the values of the attribute derived with path-pattern (can be specified multiple times)",
+			},
+			&cli.BoolFlag{
+				Name:  "include-metadata",
+				Usage: "Output each root module as an object with its backend and cloud settings",
+			},
+			&cli.BoolFlag{
+				Name:  "github-actions",
+				Usage: "Write step outputs (has_changes, roots, count, matrix) to $GITHUB_OUTPUT and a summary to $GITHUB_STEP_SUMMARY",
+			},
+			&cli.BoolFlag{
+				Name:  "check-backends",
+				Usage: "Fail the analysis if multiple root modules write to the same backend state",
 			},
 			&cli.StringFlag{
-				Name:  "log-level",
-				Value: "info",
-				Usage: "Log level (debug, info, warn, error)",
+				Name:       "log-level",
+				Value:      "info",
+				Usage:      "Log level (debug, info, warn, error)",
+				Persistent: true,
 			},
+		}, analysisFlags(), shardFlags()),
+		Commands: []*cli.Command{
+			newCheckCommand(writer),
+			newGenerateCommand(writer),
+			newGraphCommand(writer),
+			newImpactCommand(writer),
+			newListCommand(writer),
+			newParityCommand(writer),
+			newSBOMCommand(writer),
+			newValidateCommand(writer),
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			return runAnalysis(ctx, cmd, writer)
@@ -74,77 +122,183 @@ func NewApp(writer io.Writer) *cli.Command {
 
 // runAnalysis is the main action that executes the analysis
 func runAnalysis(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
-	// Setup logger
-	logLevel := parseLogLevel(cmd.String("log-level"))
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: logLevel,
-	}))
-	slog.SetDefault(logger)
+	// Record warnings so that they can be reported in the output
+	warnings := newWarningRecorder(setupLogger(cmd).Handler())
+	logger := slog.New(warnings)
+
+	outputFormat := cmd.String("output-format")
+	includeMetadata := cmd.Bool("include-metadata")
+
+	if !slices.Contains(outputFormats, outputFormat) {
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
 
-	// Parse arguments
-	beforeCommit := cmd.String("before-commit")
-	afterCommit := cmd.String("after-commit")
-	rootModuleDirs := cmd.StringSlice("root-module-dir")
-	gitRepoRootPath := cmd.String("git-repository-root-path")
-	basePath := cmd.String("base-path")
-	changedFiles := cmd.StringSlice("changed-file")
-
-	var changedFilesMap map[string]struct{}
-
-	if len(changedFiles) > 0 {
-		// Use provided changed files
-		logger.Info("Using provided changed files", "count", len(changedFiles))
-		changedFilesMap = make(map[string]struct{})
-		for _, filePath := range changedFiles {
-			absPath, err := filepath.Abs(filePath)
-			if err != nil {
-				return fmt.Errorf("failed to get absolute path for changed file %s: %w", filePath, err)
-			}
-			changedFilesMap[absPath] = struct{}{}
+	var outputTemplate *template.Template
+	if value := cmd.String("output-template"); value != "" {
+		if cmd.IsSet("output-format") {
+			return fmt.Errorf("output-template cannot be used with output-format")
 		}
-		if basePath == "" {
-			// If base-path is still not set, use current working directory
-			currentDir, err := os.Getwd()
-			if err != nil {
-				return fmt.Errorf("failed to get current working directory: %w", err)
-			}
-			basePath = currentDir
-			logger.Info("Using current working directory as base-path", "basePath", basePath)
+		var err error
+		outputTemplate, err = parseOutputTemplate(value)
+		if err != nil {
+			return err
 		}
-	} else {
-		// If git-repository-root-path is not specified, find git repository root
-		if gitRepoRootPath == "" {
-			logger.Debug("git-repository-root-path not specified, searching for git repository root")
-			repoRoot, err := findGitRepositoryRoot()
-			if err != nil {
-				return fmt.Errorf("failed to find git repository root: %w (please specify --git-repository-root-path)", err)
-			}
-			gitRepoRootPath = repoRoot
-			logger.Info("Using auto-detected git repository root", "gitRepoRootPath", gitRepoRootPath)
+	}
+
+	shardCount := int(cmd.Int("shard-count"))
+	shardIndex := int(cmd.Int("shard-index"))
+	shardWeight := cmd.String("shard-weight")
+	if !slices.Contains(shardWeights, shardWeight) {
+		return fmt.Errorf("unsupported shard weight: %s", shardWeight)
+	}
+	switch {
+	case outputFormat == outputFormatShards && shardCount < 1:
+		return fmt.Errorf("output format shards requires shard-count")
+	case cmd.IsSet("shard-index") && shardCount < 1:
+		return fmt.Errorf("shard-index requires shard-count")
+	case cmd.IsSet("shard-index") && (shardIndex < 0 || shardIndex >= shardCount):
+		return fmt.Errorf("shard-index must be between 0 and %d", shardCount-1)
+	case cmd.IsSet("shard-index") && (outputTemplate != nil || !slices.Contains([]string{outputFormatJSON, outputFormatWaves}, outputFormat)):
+		return fmt.Errorf("shard-index is only supported with the json and waves output formats")
+	}
+
+	groupBy := cmd.StringSlice("group-by")
+	if len(groupBy) > 0 {
+		if len(cmd.StringSlice("path-pattern")) == 0 {
+			return fmt.Errorf("group-by requires path-pattern")
 		}
-		// Search for changed files using git
-		var err error
-		changedFilesMap, err = searchChangedFiles(gitRepoRootPath, beforeCommit, afterCommit, logger)
+		if outputTemplate != nil || !slices.Contains([]string{outputFormatJSON, outputFormatWaves}, outputFormat) {
+			return fmt.Errorf("group-by is only supported with the json and waves output formats")
+		}
+	}
+
+	// Sharding keeps dependent root modules together, so it needs the dependency graph as well
+	ordering := outputFormat == outputFormatWaves || shardCount > 0
+	a, err := analyze(cmd, warnings, ordering, logger)
+	if err != nil {
+		return err
+	}
+
+	var shards []shard.Shard
+	if shardCount > 0 {
+		shards, err = partitionTargets(a, shardCount, shardWeight, logger)
 		if err != nil {
-			return fmt.Errorf("failed to search for changed files: %w", err)
+			return fmt.Errorf("failed to partition root modules: %w", err)
 		}
-		// If base-path is not specified, use git-repository-root-path
-		if basePath == "" {
-			basePath = gitRepoRootPath
-			logger.Info("Using git-repository-root-path as base-path", "basePath", basePath)
+		if cmd.IsSet("shard-index") {
+			a.targets = shardTargets(a.targets, shards[shardIndex])
+			logger.Info("Selected shard", "index", shardIndex, "weight", shards[shardIndex].Weight, "targets", len(a.targets))
 		}
 	}
 
-	// Validate base-path exists
-	if _, err := os.Stat(basePath); os.IsNotExist(err) {
-		return fmt.Errorf("base-path does not exist: %s", basePath)
+	options := outputOptions{
+		basePath:        a.basePath,
+		includeMetadata: includeMetadata,
+		workspaces:      len(a.conventions) > 0,
+		patterns:        a.patterns,
+	}
+	// The markdown report always explains why root modules are updated
+	if cmd.Bool("explain") || outputFormat == outputFormatMarkdown {
+		options.chains, err = collectChains(a, logger)
+		if err != nil {
+			return fmt.Errorf("failed to collect change chains: %w", err)
+		}
+	}
+	var result any
+	switch {
+	case outputTemplate != nil:
+		// Templates get the full result so that they can use any field without another flag
+		options.includeMetadata = true
+		result, err = buildResultV2(a, options, logger)
+	case outputFormat == outputFormatWaves:
+		result, err = groupTargets(a, a.targets, groupBy, func(targets []workspace.Target) (any, error) {
+			return buildWaves(a.graph, targets, options, logger)
+		})
+	case outputFormat == outputFormatJSONV2:
+		result, err = buildResultV2(a, options, logger)
+	case outputFormat == outputFormatMarkdown:
+		result, err = buildMarkdown(a, options, logger)
+	case outputFormat == outputFormatShards:
+		result, err = buildShards(shards, a.targets, options, logger)
+	default:
+		result, err = groupTargets(a, a.targets, groupBy, func(targets []workspace.Target) (any, error) {
+			return buildEntries(targets, options, logger)
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to build output: %w", err)
 	}
 
-	logger.Info("Found changed files", "count", len(changedFilesMap))
-	logger.Debug("Changed files", "files", changedFilesMap)
+	if cmd.Bool("github-actions") {
+		// The matrix always carries the metadata so that jobs can use it without another lookup
+		entries, err := buildEntries(a.targets, outputOptions{basePath: a.basePath, includeMetadata: true, workspaces: true, patterns: a.patterns}, logger)
+		if err != nil {
+			return fmt.Errorf("failed to build matrix: %w", err)
+		}
+		if err := writeGitHubActions(entries, logger); err != nil {
+			return fmt.Errorf("failed to write GitHub Actions outputs: %w", err)
+		}
+	}
 
-	// changedFiles already contains absolute paths from GetChangedFiles
-	// Find all root modules in the specified directories
+	var output []byte
+	switch {
+	case outputTemplate != nil:
+		var buf bytes.Buffer
+		if err := outputTemplate.Execute(&buf, result); err != nil {
+			return fmt.Errorf("failed to execute output template: %w", err)
+		}
+		output = buf.Bytes()
+	case outputFormat == outputFormatMarkdown:
+		output = []byte(result.(markdownReport))
+	default:
+		// Output results as JSON
+		output, err = marshalOutput(result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal output: %w", err)
+		}
+	}
+
+	// The output file is still written in quiet mode, only standard output is suppressed
+	outputFile := cmd.String("output-file")
+	if outputFile != "" || !cmd.Bool("quiet") {
+		if err := writeOutput(outputFile, output, writer); err != nil {
+			return err
+		}
+	}
+
+	if cmd.Bool("exit-code") && len(a.targets) > 0 {
+		return ErrChanges
+	}
+	return nil
+}
+
+func searchChangedFiles(gitRepoRootPath, beforeCommit, afterCommit string, logger *slog.Logger) (map[string]struct{}, error) {
+	// Validate git-repository-root-path exists
+	if _, err := os.Stat(gitRepoRootPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("git-repository-root-path does not exist: %s", gitRepoRootPath)
+	}
+
+	// Get changed files from git
+	logger.Info("Getting changed files from git")
+	changedFiles, err := gitpkg.GetChangedFiles(gitRepoRootPath, beforeCommit, afterCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changed files: %w", err)
+	}
+	return changedFiles, nil
+}
+
+// setupLogger creates the logger for the log-level flag and sets it as the default logger
+func setupLogger(cmd *cli.Command) *slog.Logger {
+	logLevel := parseLogLevel(cmd.String("log-level"))
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: logLevel,
+	}))
+	slog.SetDefault(logger)
+	return logger
+}
+
+// discoverRootModules finds all root modules in the specified directories
+func discoverRootModules(rootModuleDirs []string, logger *slog.Logger) ([]string, error) {
 	logger.Info("Searching for root modules in specified directories")
 	foundRootModuleDirs := make([]string, 0)
 	for _, dir := range rootModuleDirs {
@@ -152,7 +306,7 @@ func runAnalysis(ctx context.Context, cmd *cli.Command, writer io.Writer) error
 		logger.Info("Searching for root modules", "directory", dir)
 		foundModules, err := findRootModules(dir, logger)
 		if err != nil {
-			return fmt.Errorf("failed to find root modules in %s: %w", dir, err)
+			return nil, fmt.Errorf("failed to find root modules in %s: %w", dir, err)
 		}
 
 		if len(foundModules) == 0 {
@@ -162,57 +316,78 @@ func runAnalysis(ctx context.Context, cmd *cli.Command, writer io.Writer) error
 			foundRootModuleDirs = append(foundRootModuleDirs, foundModules...)
 		}
 	}
+	return foundRootModuleDirs, nil
+}
 
-	logger.Info("Starting analysis",
-		"before", beforeCommit,
-		"after", afterCommit,
-		"gitRepoRootPath", gitRepoRootPath,
-		"basePath", basePath,
-		"rootModuleDirs", rootModuleDirs,
-		"changedFiles", changedFilesMap,
-	)
-
-	// Analyze root modules
-	logger.Info("Analyzing root modules")
-	updatedModules, err := analyzer.AnalyzeRootModules(
-		foundRootModuleDirs,
-		changedFilesMap,
-		basePath,
-		logger,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to analyze root modules: %w", err)
+// parseWorkspaceConventions parses the workspace-var-file flag values
+func parseWorkspaceConventions(patterns []string) ([]workspace.Convention, error) {
+	conventions := make([]workspace.Convention, 0, len(patterns))
+	for _, pattern := range patterns {
+		convention, err := workspace.ParseConvention(pattern)
+		if err != nil {
+			return nil, err
+		}
+		conventions = append(conventions, convention)
 	}
+	return conventions, nil
+}
 
-	logger.Info("Analysis complete", "updatedModules", len(updatedModules))
-
-	// Output results as JSON
-	output, err := json.Marshal(updatedModules)
-	if err != nil {
-		return fmt.Errorf("failed to marshal output: %w", err)
+// resolveBasePath returns the base path for commands that do not use git,
+// defaulting to the current working directory
+func resolveBasePath(basePath string, logger *slog.Logger) (string, error) {
+	if basePath == "" {
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current working directory: %w", err)
+		}
+		basePath = currentDir
+		logger.Info("Using current working directory as base-path", "basePath", basePath)
 	}
 
-	_, err = writer.Write(output)
-	if err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
+	if _, err := os.Stat(basePath); os.IsNotExist(err) {
+		return "", fmt.Errorf("base-path does not exist: %s", basePath)
 	}
 
-	return nil
+	return basePath, nil
 }
 
-func searchChangedFiles(gitRepoRootPath, beforeCommit, afterCommit string, logger *slog.Logger) (map[string]struct{}, error) {
-	// Validate git-repository-root-path exists
-	if _, err := os.Stat(gitRepoRootPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("git-repository-root-path does not exist: %s", gitRepoRootPath)
+// findDeletedRootModules returns the absolute paths of the directories under the given search
+// directories that contained changed .tf files but no longer contain any .tf files
+func findDeletedRootModules(searchDirs []string, changedFiles map[string]struct{}) ([]string, error) {
+	absSearchDirs := make([]string, 0, len(searchDirs))
+	for _, searchDir := range searchDirs {
+		absSearchDir, err := filepath.Abs(searchDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for %s: %w", searchDir, err)
+		}
+		absSearchDirs = append(absSearchDirs, absSearchDir)
 	}
 
-	// Get changed files from git
-	logger.Info("Getting changed files from git")
-	changedFiles, err := gitpkg.GetChangedFiles(gitRepoRootPath, beforeCommit, afterCommit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get changed files: %w", err)
+	deletedModuleDirs := make([]string, 0)
+	for changedFile := range changedFiles {
+		dir := filepath.Dir(changedFile)
+		if filepath.Ext(changedFile) != ".tf" || slices.Contains(deletedModuleDirs, dir) {
+			continue
+		}
+		inSearchDirs := slices.ContainsFunc(absSearchDirs, func(searchDir string) bool {
+			relPath, err := filepath.Rel(searchDir, dir)
+			return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+		})
+		if !inSearchDirs {
+			continue
+		}
+
+		hasTerraformFiles, err := containsTerraformFiles(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to check for Terraform files in %s: %w", dir, err)
+		}
+		if !hasTerraformFiles {
+			deletedModuleDirs = append(deletedModuleDirs, dir)
+		}
 	}
-	return changedFiles, nil
+
+	slices.Sort(deletedModuleDirs)
+	return deletedModuleDirs, nil
 }
 
 // parseLogLevel parses the log level string and returns the corresponding slog.Level
@@ -239,9 +414,13 @@ func findGitRepositoryRoot() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to get current directory: %w", err)
 	}
+	return findGitRepositoryRootFrom(cwd)
+}
 
-	// Try to open git repository from current directory
-	repo, err := git.PlainOpenWithOptions(cwd, &git.PlainOpenOptions{
+// findGitRepositoryRootFrom finds the root directory of the git repository containing dir
+func findGitRepositoryRootFrom(dir string) (string, error) {
+	// Try to open git repository from the directory
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{
 		DetectDotGit: true,
 	})
 	if err != nil {
@@ -257,6 +436,17 @@ func findGitRepositoryRoot() (string, error) {
 	return worktree.Filesystem.Root(), nil
 }
 
+// repositoryRoot returns the root of the git repository containing the base path,
+// or the base path itself if it is not in a git repository
+func repositoryRoot(basePath string, logger *slog.Logger) string {
+	repoRoot, err := findGitRepositoryRootFrom(basePath)
+	if err != nil {
+		logger.Debug("Base path is not in a git repository, using it as the repository root", "basePath", basePath)
+		return basePath
+	}
+	return repoRoot
+}
+
 // findRootModules recursively searches for Terraform root modules in the given directory
 // A directory is considered a root module if it contains .tf files
 func findRootModules(searchDir string, logger *slog.Logger) ([]string, error) {
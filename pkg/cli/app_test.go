@@ -351,6 +351,16 @@ func TestRunAnalysis(t *testing.T) {
 			expectedModules: nil,
 			expectedError:   true,
 		},
+		{
+			name: "Unsupported output format",
+			args: []string{
+				"--root-module-dir", "../../mock-terraform/environments",
+				"--changed-file", "../../mock-terraform/modules/common/common-1/main.tf",
+				"--output-format", "yaml",
+			},
+			expectedModules: nil,
+			expectedError:   true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/attribute"
+	"github.com/hurack3034217/tf-mod-watcher/internal/workspace"
+)
+
+// parsePathPatterns parses the path-pattern flag values
+func parsePathPatterns(patterns []string) ([]attribute.Pattern, error) {
+	parsed := make([]attribute.Pattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		p, err := attribute.ParsePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
+}
+
+// parseFilters parses the filter flag values
+func parseFilters(filters []string) ([]attribute.Filter, error) {
+	parsed := make([]attribute.Filter, 0, len(filters))
+	for _, filter := range filters {
+		f, err := attribute.ParseFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, f)
+	}
+	return parsed, nil
+}
+
+// deriveAttributes returns the attributes of a root module derived from its path relative to the base path
+func deriveAttributes(basePath, root string, patterns []attribute.Pattern) map[string]string {
+	if len(patterns) == 0 {
+		return nil
+	}
+	absBasePath, err := filepath.Abs(basePath)
+	if err != nil {
+		return nil
+	}
+	relPath, err := filepath.Rel(absBasePath, root)
+	if err != nil {
+		return nil
+	}
+	return attribute.Derive(relPath, patterns)
+}
+
+// matchesFilters reports whether the root module satisfies the filters of the analysis
+func (a *analysis) matchesFilters(root string) bool {
+	if len(a.filters) == 0 {
+		return true
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	return attribute.MatchFilters(deriveAttributes(a.basePath, absRoot, a.patterns), a.filters)
+}
+
+// applyFilters drops the root modules that do not satisfy the filters from the results of the analysis.
+// The dependency graph is kept as is, so that ordering still sees dependencies through dropped root modules.
+func (a *analysis) applyFilters() {
+	if len(a.filters) == 0 {
+		return
+	}
+
+	keep := func(dirs []string) []string {
+		return slices.DeleteFunc(slices.Clone(dirs), func(dir string) bool {
+			return !a.matchesFilters(dir)
+		})
+	}
+	a.rootModuleDirs = keep(a.rootModuleDirs)
+	a.updatedModuleDirs = keep(a.updatedModuleDirs)
+	a.dependentModuleDirs = keep(a.dependentModuleDirs)
+	for root := range a.changedWorkspaces {
+		if !a.matchesFilters(root) {
+			delete(a.changedWorkspaces, root)
+		}
+	}
+	a.targets = slices.DeleteFunc(a.targets, func(target workspace.Target) bool {
+		return !a.matchesFilters(target.Root)
+	})
+}
+
+// groupTargets nests the output built from the targets by the values of the given attributes.
+// Targets without an attribute are grouped under an empty key.
+func groupTargets(a *analysis, targets []workspace.Target, keys []string, build func([]workspace.Target) (any, error)) (any, error) {
+	if len(keys) == 0 {
+		return build(targets)
+	}
+
+	groups := make(map[string][]workspace.Target)
+	for _, target := range targets {
+		value := deriveAttributes(a.basePath, target.Root, a.patterns)[keys[0]]
+		groups[value] = append(groups[value], target)
+	}
+
+	nested := make(map[string]any, len(groups))
+	for value, members := range groups {
+		output, err := groupTargets(a, members, keys[1:], build)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build group %s=%s: %w", keys[0], value, err)
+		}
+		nested[value] = output
+	}
+	return nested, nil
+}
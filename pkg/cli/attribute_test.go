@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunAnalysis_PathAttributes(t *testing.T) {
+	dir := t.TempDir()
+	service := `module "service" {
+  source = "../../../../modules/service"
+}`
+	testutil.WriteFiles(t, dir, map[string]string{
+		"environments/org-1/app/dev/main.tf":  service,
+		"environments/org-1/app/prod/main.tf": service,
+		"environments/org-2/db/prod/main.tf":  service,
+		"environments/shared/main.tf":         `resource "null_resource" "this" {}`,
+		"modules/service/main.tf":             `resource "null_resource" "this" {}`,
+	})
+
+	tests := []struct {
+		name      string
+		args      []string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name: "Attributes",
+			args: []string{"--path-pattern", "environments/{org}/{service}/{env}"},
+			expected: `[{"path":"environments/org-1/app/dev","attributes":{"env":"dev","org":"org-1","service":"app"}},` +
+				`{"path":"environments/org-1/app/prod","attributes":{"env":"prod","org":"org-1","service":"app"}},` +
+				`{"path":"environments/org-2/db/prod","attributes":{"env":"prod","org":"org-2","service":"db"}},` +
+				`{"path":"environments/shared"}]`,
+		},
+		{
+			name:     "Filter",
+			args:     []string{"--path-pattern", "environments/{org}/{service}/{env}", "--filter", "env=prod"},
+			expected: `[{"path":"environments/org-1/app/prod","attributes":{"env":"prod","org":"org-1","service":"app"}},{"path":"environments/org-2/db/prod","attributes":{"env":"prod","org":"org-2","service":"db"}}]`,
+		},
+		{
+			name:     "Group by",
+			args:     []string{"--path-pattern", `^environments/(?P<org>[^/]+)/[^/]+/(?P<env>[^/]+)$`, "--group-by", "env"},
+			expected: `{"":[{"path":"environments/shared"}],"dev":[{"path":"environments/org-1/app/dev","attributes":{"env":"dev","org":"org-1"}}],"prod":[{"path":"environments/org-1/app/prod","attributes":{"env":"prod","org":"org-1"}},{"path":"environments/org-2/db/prod","attributes":{"env":"prod","org":"org-2"}}]}`,
+		},
+		{
+			name:     "Nested groups with waves",
+			args:     []string{"--path-pattern", "environments/{org}/{service}/{env}", "--group-by", "org", "--group-by", "env", "--filter", "org=org-1", "--output-format", "waves"},
+			expected: `{"org-1":{"dev":[[{"path":"environments/org-1/app/dev","attributes":{"env":"dev","org":"org-1","service":"app"}}]],"prod":[[{"path":"environments/org-1/app/prod","attributes":{"env":"prod","org":"org-1","service":"app"}}]]}}`,
+		},
+		{
+			name:      "Group by without path pattern",
+			args:      []string{"--group-by", "env"},
+			expectErr: true,
+		},
+		{
+			name:      "Group by with json-v2",
+			args:      []string{"--path-pattern", "environments/{org}/{service}/{env}", "--group-by", "env", "--output-format", "json-v2"},
+			expectErr: true,
+		},
+		{
+			name:      "Invalid filter",
+			args:      []string{"--path-pattern", "environments/{org}/{service}/{env}", "--filter", "prod"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewApp(&buf).Run(context.Background(), append([]string{
+				os.Args[0],
+				"--root-module-dir", filepath.Join(dir, "environments"),
+				"--base-path", dir,
+				"--changed-file", filepath.Join(dir, "modules", "service", "main.tf"),
+				"--changed-file", filepath.Join(dir, "environments", "shared", "main.tf"),
+				"--log-level", "error",
+			}, tt.args...))
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewApp().Run() failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected output %s, got %s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestRunAnalysis_PathAttributesJSONV2(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/dev/main.tf":  `resource "null_resource" "this" {}`,
+		"envs/prod/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	var buf bytes.Buffer
+	err := NewApp(&buf).Run(context.Background(), []string{
+		os.Args[0],
+		"--root-module-dir", filepath.Join(dir, "envs"),
+		"--base-path", dir,
+		"--changed-file", filepath.Join(dir, "envs", "prod", "main.tf"),
+		"--changed-file", filepath.Join(dir, "envs", "staging", "main.tf"),
+		"--path-pattern", "envs/{env}",
+		"--filter", "env=prod",
+		"--filter", "env=staging",
+		"--output-format", "json-v2",
+		"--log-level", "error",
+	})
+	if err != nil {
+		t.Fatalf("NewApp().Run() failed: %v", err)
+	}
+	checkSchema(t, "result-v2.schema.json", buf.Bytes())
+
+	var result resultV2
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if len(result.Roots) != 2 {
+		t.Fatalf("Expected 2 roots, got %s", buf.String())
+	}
+	for i, expected := range []struct{ path, status, env string }{
+		{"envs/prod", statusUpdated, "prod"},
+		{"envs/staging", statusDeleted, "staging"},
+	} {
+		root := result.Roots[i]
+		if root.Path != expected.path || root.Status != expected.status || root.Attributes["env"] != expected.env {
+			t.Errorf("Expected %s to be %s with env %s, got %+v", expected.path, expected.status, expected.env, root)
+		}
+	}
+}
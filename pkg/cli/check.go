@@ -0,0 +1,411 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/analyzer"
+	"github.com/hurack3034217/tf-mod-watcher/internal/config"
+	"github.com/hurack3034217/tf-mod-watcher/internal/drift"
+	"github.com/hurack3034217/tf-mod-watcher/internal/layer"
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+	"github.com/hurack3034217/tf-mod-watcher/internal/stack"
+)
+
+// newCheckCommand creates the check command and its subcommands
+func newCheckCommand(writer io.Writer) *cli.Command {
+	return &cli.Command{
+		Name:  "check",
+		Usage: "Runs static checks on the root modules",
+		Commands: []*cli.Command{
+			{
+				Name:  "backends",
+				Usage: "Reports root modules that write to the same backend state",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runCheckBackends(ctx, cmd, writer)
+				},
+			},
+			{
+				Name:  "unused",
+				Usage: "Reports local modules that no root module uses",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:     "module-dir",
+						Usage:    "Directory to search for local modules (can be specified multiple times)",
+						Required: true,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runCheckUnused(ctx, cmd, writer)
+				},
+			},
+			{
+				Name:  "layers",
+				Usage: "Reports module blocks that break the layer rules of the configuration",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "config",
+						Usage: "Path to the configuration file, relative to base-path",
+						Value: config.DefaultFileName,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runCheckLayers(ctx, cmd, writer)
+				},
+			},
+			{
+				Name:  "versions",
+				Usage: "Reports remote modules pinned to different versions across the root and child modules",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output format (text, json)",
+						Value: reportFormatText,
+					},
+					&cli.BoolFlag{
+						Name:  "fail-on-outdated",
+						Usage: "Exit with an error if any module pins an older version than the newest one used for the same source",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runCheckVersions(ctx, cmd, writer)
+				},
+			},
+		},
+	}
+}
+
+// runCheckBackends reports backend state collisions between root modules
+func runCheckBackends(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+
+	foundRootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Checking backend state collisions", "rootModules", len(foundRootModuleDirs))
+	collisions, err := stack.FindStateCollisions(foundRootModuleDirs, logger)
+	if err != nil {
+		return fmt.Errorf("failed to check backends: %w", err)
+	}
+
+	if len(collisions) == 0 {
+		_, err = fmt.Fprintln(writer, "No backend state collisions found")
+		if err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	var report strings.Builder
+	for _, collision := range collisions {
+		roots, err := analyzer.ConvertToRelativePaths(basePath, collision.Roots, logger)
+		if err != nil {
+			return fmt.Errorf("failed to convert paths: %w", err)
+		}
+		fmt.Fprintf(&report, "State %s is shared by %d root modules:\n", collision.Location, len(roots))
+		for _, root := range roots {
+			fmt.Fprintf(&report, "  - %s\n", root)
+		}
+	}
+
+	_, err = io.WriteString(writer, report.String())
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return fmt.Errorf("found %d backend state collisions", len(collisions))
+}
+
+// checkBackendCollisions logs backend state collisions between root modules and
+// returns an error if any are found
+func checkBackendCollisions(rootModuleDirs []string, basePath string, logger *slog.Logger) error {
+	logger.Info("Checking backend state collisions", "rootModules", len(rootModuleDirs))
+	collisions, err := stack.FindStateCollisions(rootModuleDirs, logger)
+	if err != nil {
+		return fmt.Errorf("failed to check backends: %w", err)
+	}
+
+	for _, collision := range collisions {
+		roots, err := analyzer.ConvertToRelativePaths(basePath, collision.Roots, logger)
+		if err != nil {
+			return fmt.Errorf("failed to convert paths: %w", err)
+		}
+		logger.Error("Multiple root modules write to the same state", "state", collision.Location, "roots", roots)
+	}
+
+	if len(collisions) > 0 {
+		return fmt.Errorf("found %d backend state collisions", len(collisions))
+	}
+	return nil
+}
+
+// runCheckUnused reports the module directories under the module directories that are reachable from no root module
+func runCheckUnused(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+	rootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+
+	graph, err := modulegraph.Build(rootModuleDirs, basePath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to build module graph: %w", err)
+	}
+
+	unused := make([]string, 0)
+	for _, searchDir := range cmd.StringSlice("module-dir") {
+		moduleDirs, err := findRootModules(searchDir, logger)
+		if err != nil {
+			return fmt.Errorf("failed to find modules in %s: %w", searchDir, err)
+		}
+		for _, moduleDir := range moduleDirs {
+			absModuleDir, err := filepath.Abs(moduleDir)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path for %s: %w", moduleDir, err)
+			}
+			if _, used := graph.NodeID(absModuleDir); !used && !slices.Contains(unused, absModuleDir) {
+				unused = append(unused, absModuleDir)
+			}
+		}
+	}
+
+	if len(unused) == 0 {
+		_, err = fmt.Fprintln(writer, "No unused modules found")
+		if err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	modules, err := analyzer.ConvertToRelativePaths(basePath, unused, logger)
+	if err != nil {
+		return fmt.Errorf("failed to convert paths: %w", err)
+	}
+	slices.Sort(modules)
+
+	var report strings.Builder
+	verb := "are"
+	if len(modules) == 1 {
+		verb = "is"
+	}
+	fmt.Fprintf(&report, "%s %s not used by any root module:\n", pluralize(len(modules), "module"), verb)
+	for _, module := range modules {
+		fmt.Fprintf(&report, "  - %s\n", module)
+	}
+
+	_, err = io.WriteString(writer, report.String())
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return fmt.Errorf("found %s", pluralize(len(modules), "unused module"))
+}
+
+// runCheckLayers reports the module blocks using modules of a layer their own layer may not use
+func runCheckLayers(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+	configPath := cmd.String("config")
+	if !filepath.IsAbs(configPath) {
+		configPath = filepath.Join(basePath, configPath)
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Layers) == 0 {
+		return fmt.Errorf("no layers defined in %s", configPath)
+	}
+	rules, err := layer.NewRules(cfg.Layers)
+	if err != nil {
+		return err
+	}
+
+	rootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+	graph, err := modulegraph.Build(rootModuleDirs, basePath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to build module graph: %w", err)
+	}
+
+	violations := rules.Check(graph)
+	if len(violations) == 0 {
+		_, err = fmt.Fprintln(writer, "No layer violations found")
+		if err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	var report strings.Builder
+	for _, violation := range violations {
+		fmt.Fprintf(&report, "%s:%d: module %q: %s (%s) must not use %s (%s)\n",
+			relativeFile(basePath, violation.Edge.File), violation.Edge.Line, violation.Edge.Name,
+			violation.Edge.From, violation.FromLayer, violation.Edge.To, violation.ToLayer)
+	}
+
+	_, err = io.WriteString(writer, report.String())
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return fmt.Errorf("found %d layer violations", len(violations))
+}
+
+// versionReport is the JSON output of the check versions command
+type versionReport struct {
+	Sources []versionSource `json:"sources"`
+}
+
+// versionSource is a remote module source and the versions its module blocks pin
+type versionSource struct {
+	Source   string         `json:"source"`
+	Newest   string         `json:"newest"`
+	Versions []string       `json:"versions"`
+	Usages   []versionUsage `json:"usages"`
+}
+
+// versionUsage is a module block calling a remote module
+type versionUsage struct {
+	Module  string `json:"module"`
+	Name    string `json:"name"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Version string `json:"version"`
+	Status  string `json:"status"`
+}
+
+// runCheckVersions reports the versions of every remote module source and the module blocks pinning
+// an older version than the newest one used for the same source. Outdated pins only fail with --fail-on-outdated.
+func runCheckVersions(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	format := cmd.String("format")
+	if err := validateReportFormat(format); err != nil {
+		return err
+	}
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+	rootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+	graph, err := modulegraph.Build(rootModuleDirs, basePath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to build module graph: %w", err)
+	}
+
+	report := versionReport{Sources: make([]versionSource, 0)}
+	spread, outdated := 0, 0
+	for _, source := range drift.Collect(graph) {
+		usages := make([]versionUsage, 0, len(source.Usages))
+		for _, usage := range source.Usages {
+			usages = append(usages, versionUsage{
+				Module:  usage.Module,
+				Name:    usage.Name,
+				File:    relativeFile(basePath, usage.File),
+				Line:    usage.Line,
+				Version: usage.Version,
+				Status:  usage.Status,
+			})
+			if usage.Status == drift.StatusOutdated {
+				outdated++
+			}
+		}
+		if len(source.Versions) > 1 {
+			spread++
+		}
+		report.Sources = append(report.Sources, versionSource{
+			Source:   source.Address,
+			Newest:   source.Newest,
+			Versions: source.Versions,
+			Usages:   usages,
+		})
+	}
+
+	err = writeReport(writer, format, report, func(text *strings.Builder) {
+		if len(report.Sources) == 0 {
+			text.WriteString("No remote modules found\n")
+			return
+		}
+		table := tabwriter.NewWriter(text, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "SOURCE\tVERSION\tSTATUS\tMODULE\tNAME\tFILE")
+		for _, source := range report.Sources {
+			for _, usage := range source.Usages {
+				fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s:%d\n",
+					source.Source, cmp.Or(usage.Version, "-"), usage.Status, usage.Module, usage.Name, usage.File, usage.Line)
+			}
+		}
+		table.Flush()
+		fmt.Fprintf(text, "%s, %d with version spread, %s\n",
+			pluralize(len(report.Sources), "remote source"), spread, pluralize(outdated, "outdated pin"))
+	})
+	if err != nil {
+		return err
+	}
+
+	if outdated > 0 && cmd.Bool("fail-on-outdated") {
+		return fmt.Errorf("found %s", pluralize(outdated, "outdated module pin"))
+	}
+	return nil
+}
+
+// warnUnusedChanges logs a warning for every changed file in a module directory that is reachable
+// from no root module, since changing it has no effect
+func warnUnusedChanges(graph *modulegraph.Graph, changedFiles map[string]struct{}, logger *slog.Logger) error {
+	changedModuleDirs := make(map[string][]string)
+	for file := range changedFiles {
+		dir := filepath.Dir(file)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		hasTerraformFiles, err := containsTerraformFiles(dir)
+		if err != nil {
+			return fmt.Errorf("failed to check for Terraform files in %s: %w", dir, err)
+		}
+		if hasTerraformFiles {
+			changedModuleDirs[dir] = append(changedModuleDirs[dir], file)
+		}
+	}
+
+	for _, dir := range slices.Sorted(maps.Keys(changedModuleDirs)) {
+		if _, used := graph.NodeID(dir); used {
+			continue
+		}
+		files := changedModuleDirs[dir]
+		slices.Sort(files)
+		logger.Warn("Changed files are in a module that no root module uses", "module", dir, "files", files)
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunCheckBackends(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/a/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "shared.tfstate"
+  }
+}`,
+		"roots/b/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "shared.tfstate"
+  }
+}`,
+		"roots/c/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "c.tfstate"
+  }
+}`,
+	})
+
+	tests := []struct {
+		name           string
+		rootModuleDirs []string
+		expectedOutput string
+		expectedError  bool
+	}{
+		{
+			name:           "Collision found",
+			rootModuleDirs: []string{filepath.Join(dir, "roots")},
+			expectedOutput: "State s3;bucket=tfstate;key=shared.tfstate is shared by 2 root modules:\n  - roots/a\n  - roots/b\n",
+			expectedError:  true,
+		},
+		{
+			name:           "No collision",
+			rootModuleDirs: []string{filepath.Join(dir, "roots", "a"), filepath.Join(dir, "roots", "c")},
+			expectedOutput: "No backend state collisions found\n",
+			expectedError:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := []string{os.Args[0], "check", "backends", "--base-path", dir, "--log-level", "error"}
+			for _, rootModuleDir := range tt.rootModuleDirs {
+				args = append(args, "--root-module-dir", rootModuleDir)
+			}
+
+			err := NewApp(&buf).Run(context.Background(), args)
+			if tt.expectedError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectedError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, buf.String())
+			}
+		})
+	}
+}
+
+func TestRunAnalysis_CheckBackends(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/a/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "shared.tfstate"
+  }
+}`,
+		"roots/b/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "shared.tfstate"
+  }
+}`,
+	})
+
+	var buf bytes.Buffer
+	err := NewApp(&buf).Run(context.Background(), []string{
+		os.Args[0],
+		"--root-module-dir", filepath.Join(dir, "roots"),
+		"--changed-file", filepath.Join(dir, "roots", "a", "main.tf"),
+		"--check-backends",
+		"--log-level", "error",
+	})
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if !strings.Contains(err.Error(), "backend state collisions") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output, got %s", buf.String())
+	}
+}
+
+func TestRunCheckUnused(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "service" {
+  source = "../../modules/service"
+}`,
+		"modules/service/main.tf": `module "db" {
+  source = "./db"
+}`,
+		"modules/service/db/main.tf":   `resource "null_resource" "this" {}`,
+		"modules/legacy/main.tf":       `resource "null_resource" "this" {}`,
+		"modules/legacy/copy/main.tf":  `resource "null_resource" "this" {}`,
+		"modules/legacy/README.md":     "# legacy",
+		"modules/service/db/README.md": "# db",
+	})
+
+	tests := []struct {
+		name           string
+		moduleDirs     []string
+		expectedOutput string
+		expectedError  bool
+	}{
+		{
+			name:           "Unused modules found",
+			moduleDirs:     []string{filepath.Join(dir, "modules")},
+			expectedOutput: "2 modules are not used by any root module:\n  - modules/legacy\n  - modules/legacy/copy\n",
+			expectedError:  true,
+		},
+		{
+			name:           "Single unused module",
+			moduleDirs:     []string{filepath.Join(dir, "modules", "legacy", "copy")},
+			expectedOutput: "1 module is not used by any root module:\n  - modules/legacy/copy\n",
+			expectedError:  true,
+		},
+		{
+			name:           "No unused modules",
+			moduleDirs:     []string{filepath.Join(dir, "modules", "service")},
+			expectedOutput: "No unused modules found\n",
+			expectedError:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := []string{os.Args[0], "check", "unused", "--root-module-dir", filepath.Join(dir, "envs"), "--base-path", dir, "--log-level", "error"}
+			for _, moduleDir := range tt.moduleDirs {
+				args = append(args, "--module-dir", moduleDir)
+			}
+
+			err := NewApp(&buf).Run(context.Background(), args)
+			if tt.expectedError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectedError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, buf.String())
+			}
+		})
+	}
+}
+
+func TestRunCheckLayers(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		".tf-mod-watcher.yaml": `layers:
+  - name: environments
+    paths: ["environments/**"]
+    allow: [usecases, modules]
+  - name: usecases
+    paths: ["usecases/*"]
+    allow: [modules]
+  - name: modules
+    paths: ["modules/**"]
+`,
+		"strict.yaml": `layers:
+  - name: environments
+    paths: ["environments/**"]
+    allow: [usecases]
+  - name: usecases
+    paths: ["usecases/*"]
+    allow: [modules]
+  - name: modules
+    paths: ["modules/**"]
+`,
+		"loose.yaml": `layers:
+  - name: environments
+    paths: ["environments/**"]
+    allow: [shared]
+  - name: shared
+    paths: ["usecases/*", "modules/**"]
+`,
+		"environments/prod/main.tf": `module "service" {
+  source = "../../usecases/service"
+}
+module "network" {
+  source = "../../modules/network"
+}`,
+		"usecases/service/main.tf": `module "network" {
+  source = "../../modules/network"
+}`,
+		"modules/network/main.tf": `module "vpc" {
+  source = "./vpc"
+}
+
+module "service" {
+  source = "../../usecases/service"
+}`,
+		"modules/network/vpc/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	tests := []struct {
+		name           string
+		config         string
+		expectedOutput string
+		expectedError  bool
+	}{
+		{
+			name: "Layer violations found",
+			expectedOutput: `modules/network/main.tf:6: module "service": modules/network (modules) must not use usecases/service (usecases)
+`,
+			expectedError: true,
+		},
+		{
+			name:   "Stricter rules",
+			config: filepath.Join(dir, "strict.yaml"),
+			expectedOutput: `environments/prod/main.tf:5: module "network": environments/prod (environments) must not use modules/network (modules)
+modules/network/main.tf:6: module "service": modules/network (modules) must not use usecases/service (usecases)
+`,
+			expectedError: true,
+		},
+		{
+			name:           "No layer violations",
+			config:         "loose.yaml",
+			expectedOutput: "No layer violations found\n",
+			expectedError:  false,
+		},
+		{
+			name:          "Missing config",
+			config:        "missing.yaml",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := []string{os.Args[0], "check", "layers", "--root-module-dir", filepath.Join(dir, "environments"), "--base-path", dir, "--log-level", "error"}
+			if tt.config != "" {
+				args = append(args, "--config", tt.config)
+			}
+
+			err := NewApp(&buf).Run(context.Background(), args)
+			if tt.expectedError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectedError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, buf.String())
+			}
+		})
+	}
+}
+
+func TestRunCheckVersions(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/dev/main.tf": `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}`,
+		"envs/prod/main.tf": `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}
+
+module "bucket" {
+  source = "git::https://example.com/bucket.git?ref=v1.2.0"
+}`,
+		"envs/stg/main.tf": `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}`,
+		"local/app/main.tf": `module "network" {
+  source = "../../modules/network"
+}`,
+		"modules/network/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	tests := []struct {
+		name           string
+		rootModuleDir  string
+		format         string
+		args           []string
+		expectedOutput string
+		expectedError  bool
+	}{
+		{
+			name:          "Outdated pins found",
+			rootModuleDir: filepath.Join(dir, "envs"),
+			format:        "text",
+			expectedOutput: `SOURCE                               VERSION  STATUS    MODULE     NAME    FILE
+git::https://example.com/bucket.git  v1.2.0   newest    envs/prod  bucket  envs/prod/main.tf:7
+terraform-aws-modules/vpc/aws        5.1.0    newest    envs/prod  vpc     envs/prod/main.tf:2
+terraform-aws-modules/vpc/aws        5.1.0    newest    envs/stg   vpc     envs/stg/main.tf:2
+terraform-aws-modules/vpc/aws        5.0.0    outdated  envs/dev   vpc     envs/dev/main.tf:2
+2 remote sources, 1 with version spread, 1 outdated pin
+`,
+			expectedError: false,
+		},
+		{
+			name:          "Fail on outdated pins",
+			rootModuleDir: filepath.Join(dir, "envs"),
+			format:        "text",
+			args:          []string{"--fail-on-outdated"},
+			expectedOutput: `SOURCE                               VERSION  STATUS    MODULE     NAME    FILE
+git::https://example.com/bucket.git  v1.2.0   newest    envs/prod  bucket  envs/prod/main.tf:7
+terraform-aws-modules/vpc/aws        5.1.0    newest    envs/prod  vpc     envs/prod/main.tf:2
+terraform-aws-modules/vpc/aws        5.1.0    newest    envs/stg   vpc     envs/stg/main.tf:2
+terraform-aws-modules/vpc/aws        5.0.0    outdated  envs/dev   vpc     envs/dev/main.tf:2
+2 remote sources, 1 with version spread, 1 outdated pin
+`,
+			expectedError: true,
+		},
+		{
+			name:          "JSON output",
+			rootModuleDir: filepath.Join(dir, "envs", "prod"),
+			format:        "json",
+			expectedOutput: `{"sources":[{"source":"git::https://example.com/bucket.git","newest":"v1.2.0","versions":["v1.2.0"],"usages":[{"module":"envs/prod","name":"bucket","file":"envs/prod/main.tf","line":7,"version":"v1.2.0","status":"newest"}]},{"source":"terraform-aws-modules/vpc/aws","newest":"5.1.0","versions":["5.1.0"],"usages":[{"module":"envs/prod","name":"vpc","file":"envs/prod/main.tf","line":2,"version":"5.1.0","status":"newest"}]}]}
+`,
+			expectedError: false,
+		},
+		{
+			name:           "No remote modules",
+			rootModuleDir:  filepath.Join(dir, "local"),
+			format:         "text",
+			expectedOutput: "No remote modules found\n",
+			expectedError:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := append([]string{os.Args[0], "check", "versions", "--root-module-dir", tt.rootModuleDir, "--base-path", dir, "--format", tt.format, "--log-level", "error"}, tt.args...)
+
+			err := NewApp(&buf).Run(context.Background(), args)
+			if tt.expectedError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectedError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/analyzer"
+	"github.com/hurack3034217/tf-mod-watcher/internal/atlantis"
+	"github.com/hurack3034217/tf-mod-watcher/internal/metadata"
+	"github.com/hurack3034217/tf-mod-watcher/internal/pipeline"
+	"github.com/hurack3034217/tf-mod-watcher/internal/stack"
+	"github.com/hurack3034217/tf-mod-watcher/internal/workspace"
+)
+
+// newGenerateCommand creates the generate command and its subcommands
+func newGenerateCommand(writer io.Writer) *cli.Command {
+	return &cli.Command{
+		Name:  "generate",
+		Usage: "Generates CI configuration for the root modules",
+		Commands: []*cli.Command{
+			{
+				Name:                   "pipeline",
+				Usage:                  "Generates a dynamic pipeline with jobs for the updated root modules",
+				MutuallyExclusiveFlags: changeSourceFlags(),
+				Flags: slices.Concat([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "format",
+						Usage:    "Pipeline format (" + strings.Join(pipeline.Formats(), ", ") + ")",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "template",
+						Usage:    "Path to a Go text/template rendering the jobs of a root module",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "output",
+						Usage: "Path to write the pipeline to (default: standard output)",
+					},
+				}, analysisFlags()),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runGeneratePipeline(ctx, cmd, writer)
+				},
+			},
+			{
+				Name:  "atlantis",
+				Usage: "Generates atlantis.yaml with a project per root module",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "output",
+						Usage: "Path to write atlantis.yaml to, relative to base-path",
+						Value: "atlantis.yaml",
+					},
+					&cli.BoolFlag{
+						Name:  "check",
+						Usage: "Fail if the existing file is not up to date instead of writing it",
+					},
+					&cli.BoolFlag{
+						Name:  "depends-on",
+						Usage: "Add depends_on to projects from the dependencies between root modules",
+					},
+					&cli.StringSliceFlag{
+						Name:  "stack-dependency",
+						Usage: "Explicit dependency between root modules in the form <root>=<depends-on> (can be specified multiple times)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runGenerateAtlantis(ctx, cmd, writer)
+				},
+			},
+		},
+	}
+}
+
+// runGeneratePipeline renders the job template for the updated root modules into a pipeline
+func runGeneratePipeline(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	warnings := newWarningRecorder(setupLogger(cmd).Handler())
+	logger := slog.New(warnings)
+
+	format := cmd.String("format")
+	if !slices.Contains(pipeline.Formats(), format) {
+		return fmt.Errorf("unsupported pipeline format: %s", format)
+	}
+	tmpl, err := parseTemplateFile(cmd.String("template"))
+	if err != nil {
+		return err
+	}
+
+	a, err := analyze(cmd, warnings, true, logger)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := buildJobs(a, logger)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Generating pipeline", "format", format, "jobs", len(jobs))
+	document, err := pipeline.Generate(format, tmpl, jobs)
+	if err != nil {
+		return fmt.Errorf("failed to generate pipeline: %w", err)
+	}
+
+	return writeOutput(cmd.String("output"), document, writer)
+}
+
+// buildJobs converts the targets to pipeline jobs in dependency order
+func buildJobs(a *analysis, logger *slog.Logger) ([]pipeline.Job, error) {
+	roots := make([]string, 0)
+	for _, target := range a.targets {
+		if !slices.Contains(roots, target.Root) {
+			roots = append(roots, target.Root)
+		}
+	}
+	waves, err := a.graph.Waves(roots)
+	if err != nil {
+		return nil, fmt.Errorf("failed to order root modules: %w", err)
+	}
+
+	relPaths, err := analyzer.ConvertToRelativePaths(a.basePath, roots, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert paths: %w", err)
+	}
+	relPathOf := make(map[string]string, len(roots))
+	for i, root := range roots {
+		relPathOf[root] = relPaths[i]
+	}
+	targetSlug := func(target workspace.Target) string {
+		return slug(relPathOf[target.Root], target.Workspace)
+	}
+
+	jobs := make([]pipeline.Job, 0, len(a.targets))
+	for _, wave := range waves {
+		for _, root := range wave {
+			rootMetadata, err := metadata.Collect(root, a.basePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to collect metadata of %s: %w", root, err)
+			}
+			dependencies := a.graph.SelectedDependencies(root, roots)
+
+			for _, target := range a.targets {
+				if target.Root != root {
+					continue
+				}
+
+				dependsOn := make([]string, 0)
+				for _, dependency := range dependencies {
+					// A workspace depends on the same workspace of the dependency if it has one
+					dependencyTargets := make([]workspace.Target, 0)
+					for _, other := range a.targets {
+						if other.Root == dependency {
+							dependencyTargets = append(dependencyTargets, other)
+						}
+					}
+					sameWorkspace := slices.IndexFunc(dependencyTargets, func(other workspace.Target) bool {
+						return other.Workspace == target.Workspace
+					})
+					if sameWorkspace >= 0 {
+						dependencyTargets = dependencyTargets[sameWorkspace : sameWorkspace+1]
+					}
+					for _, dependencyTarget := range dependencyTargets {
+						dependsOn = append(dependsOn, targetSlug(dependencyTarget))
+					}
+				}
+
+				jobs = append(jobs, pipeline.Job{
+					Path:       relPathOf[root],
+					Slug:       targetSlug(target),
+					Workspace:  target.Workspace,
+					Attributes: deriveAttributes(a.basePath, root, a.patterns),
+					Metadata:   rootMetadata,
+					DependsOn:  dependsOn,
+				})
+			}
+		}
+	}
+
+	return jobs, nil
+}
+
+// runGenerateAtlantis writes atlantis.yaml, or checks that it is up to date
+func runGenerateAtlantis(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+
+	foundRootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+	roots := make([]string, 0, len(foundRootModuleDirs))
+	for _, dir := range foundRootModuleDirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
+		}
+		if !slices.Contains(roots, absDir) {
+			roots = append(roots, absDir)
+		}
+	}
+	slices.Sort(roots)
+
+	var graph *stack.Graph
+	if cmd.Bool("depends-on") {
+		logger.Info("Building dependency graph between root modules")
+		graph, err = buildStackGraph(roots, cmd.StringSlice("stack-dependency"), logger)
+		if err != nil {
+			return fmt.Errorf("failed to build dependency graph: %w", err)
+		}
+	}
+
+	projects, err := buildProjects(basePath, roots, graph, logger)
+	if err != nil {
+		return err
+	}
+	document, err := atlantis.Marshal(atlantis.NewConfig(projects))
+	if err != nil {
+		return err
+	}
+
+	output := cmd.String("output")
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(basePath, output)
+	}
+
+	if cmd.Bool("check") {
+		existing, err := os.ReadFile(output)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", output, err)
+		}
+		if !bytes.Equal(existing, document) {
+			return fmt.Errorf("%s is out of date, run generate atlantis to update it", output)
+		}
+		_, err = fmt.Fprintf(writer, "%s is up to date\n", output)
+		if err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	logger.Info("Writing Atlantis configuration", "path", output, "projects", len(projects))
+	return writeOutput(output, document, writer)
+}
+
+// buildProjects creates an Atlantis project for every root module. Projects are named
+// after the relative path of the root module and depend on its direct dependencies if graph is set.
+func buildProjects(basePath string, roots []string, graph *stack.Graph, logger *slog.Logger) ([]atlantis.Project, error) {
+	relPaths, err := analyzer.ConvertToRelativePaths(basePath, roots, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert paths: %w", err)
+	}
+	nameOf := make(map[string]string, len(roots))
+	for i, root := range roots {
+		nameOf[root] = filepath.ToSlash(relPaths[i])
+	}
+
+	projects := make([]atlantis.Project, 0, len(roots))
+	for _, root := range roots {
+		whenModified, err := atlantis.WhenModified(root, basePath, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect files of %s: %w", root, err)
+		}
+
+		project := atlantis.Project{
+			Name:     nameOf[root],
+			Dir:      nameOf[root],
+			Autoplan: atlantis.Autoplan{Enabled: true, WhenModified: whenModified},
+		}
+		if graph != nil {
+			for _, dependency := range graph.Dependencies(root) {
+				if name, ok := nameOf[dependency]; ok {
+					project.DependsOn = append(project.DependsOn, name)
+				}
+			}
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, nil
+}
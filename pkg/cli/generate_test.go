@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunGeneratePipeline(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/network/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"roots/app/main.tf": `data "terraform_remote_state" "network" {
+  backend = "s3"
+  config = {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"roots/db/main.tf": `resource "null_resource" "this" {}`,
+		"gitlab.tmpl": `plan-{{ .Slug }}:
+  script: ["terraform -chdir={{ .Path }} plan"]
+  variables:
+    BACKEND: {{ .Metadata.Backend.Type }}
+`,
+		"buildkite.tmpl": `label: "plan {{ .Path }}"
+command: terraform -chdir={{ .Path }} plan
+`,
+	})
+
+	tests := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "GitLab",
+			format: "gitlab",
+			expected: `plan-roots-network:
+  script: ["terraform -chdir=roots/network plan"]
+  variables:
+    BACKEND: s3
+plan-roots-app:
+  script: ["terraform -chdir=roots/app plan"]
+  variables:
+    BACKEND: local
+  needs:
+    - plan-roots-network
+`,
+		},
+		{
+			name:   "Buildkite",
+			format: "buildkite",
+			expected: `steps:
+  - label: "plan roots/network"
+    command: terraform -chdir=roots/network plan
+    key: roots-network
+  - label: "plan roots/app"
+    command: terraform -chdir=roots/app plan
+    key: roots-app
+    depends_on:
+      - roots-network
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "pipeline.yml")
+
+			var buf bytes.Buffer
+			err := NewApp(&buf).Run(context.Background(), []string{
+				os.Args[0],
+				"generate", "pipeline",
+				"--root-module-dir", filepath.Join(dir, "roots"),
+				"--base-path", dir,
+				"--changed-file", filepath.Join(dir, "roots", "network", "main.tf"),
+				"--changed-file", filepath.Join(dir, "roots", "app", "main.tf"),
+				"--format", tt.format,
+				"--template", filepath.Join(dir, tt.format+".tmpl"),
+				"--output", output,
+				"--log-level", "error",
+			})
+			if err != nil {
+				t.Fatalf("NewApp().Run() failed: %v", err)
+			}
+
+			document, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("Failed to read pipeline: %v", err)
+			}
+			if string(document) != tt.expected {
+				t.Errorf("Expected pipeline:\n%s\ngot:\n%s", tt.expected, document)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("Expected no standard output, got %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestRunGeneratePipeline_UnsupportedFormat(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/app/main.tf": `resource "null_resource" "this" {}`,
+		"job.tmpl":          `plan: {}`,
+	})
+
+	err := NewApp(&bytes.Buffer{}).Run(context.Background(), []string{
+		os.Args[0],
+		"generate", "pipeline",
+		"--root-module-dir", filepath.Join(dir, "roots"),
+		"--changed-file", filepath.Join(dir, "roots", "app", "main.tf"),
+		"--format", "jenkins",
+		"--template", filepath.Join(dir, "job.tmpl"),
+		"--log-level", "error",
+	})
+	if err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestRunGenerateAtlantis(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/network/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"roots/app/main.tf": `module "service" {
+  source = "../../modules/service"
+}
+data "terraform_remote_state" "network" {
+  backend = "s3"
+  config = {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"modules/service/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	run := func(args ...string) (string, error) {
+		var buf bytes.Buffer
+		err := NewApp(&buf).Run(context.Background(), append([]string{
+			os.Args[0],
+			"generate", "atlantis",
+			"--root-module-dir", filepath.Join(dir, "roots"),
+			"--base-path", dir,
+			"--depends-on",
+			"--log-level", "error",
+		}, args...))
+		return buf.String(), err
+	}
+
+	if _, err := run(); err != nil {
+		t.Fatalf("NewApp().Run() failed: %v", err)
+	}
+
+	document, err := os.ReadFile(filepath.Join(dir, "atlantis.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read atlantis.yaml: %v", err)
+	}
+	expected := `# Code generated by tf-mod-watcher generate atlantis. DO NOT EDIT.
+version: 3
+projects:
+  - name: roots/app
+    dir: roots/app
+    autoplan:
+      enabled: true
+      when_modified:
+        - '*'
+        - ../../modules/service/*
+        - ../.terraform-version
+        - ../.opentofu-version
+        - ../.tool-versions
+        - ../../.terraform-version
+        - ../../.opentofu-version
+        - ../../.tool-versions
+    depends_on:
+      - roots/network
+  - name: roots/network
+    dir: roots/network
+    autoplan:
+      enabled: true
+      when_modified:
+        - '*'
+        - ../.terraform-version
+        - ../.opentofu-version
+        - ../.tool-versions
+        - ../../.terraform-version
+        - ../../.opentofu-version
+        - ../../.tool-versions
+`
+	if string(document) != expected {
+		t.Errorf("Expected atlantis.yaml:\n%s\ngot:\n%s", expected, document)
+	}
+
+	if _, err := run("--check"); err != nil {
+		t.Errorf("Expected up to date check to pass, got %v", err)
+	}
+
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/db/main.tf": `resource "null_resource" "this" {}`,
+	})
+	if _, err := run("--check"); err == nil {
+		t.Error("Expected check to fail after adding a root module")
+	}
+}
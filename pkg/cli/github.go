@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Environment variables GitHub Actions sets to the files a step writes its outputs and summary to
+const (
+	githubOutputEnv      = "GITHUB_OUTPUT"
+	githubStepSummaryEnv = "GITHUB_STEP_SUMMARY"
+)
+
+// slugInvalidChars matches runs of characters that are not allowed in slugs
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// matrixEntry is a target in the matrix include list for GitHub Actions
+type matrixEntry struct {
+	rootEntry
+	Slug string `json:"slug"`
+}
+
+// matrix is a matrix strategy for GitHub Actions
+type matrix struct {
+	Include []matrixEntry `json:"include"`
+}
+
+// slug converts a path and an optional workspace to a name usable in job names, artifacts and file names
+func slug(path, workspace string) string {
+	if workspace != "" {
+		path += "-" + workspace
+	}
+	return strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(path), "-"), "-")
+}
+
+// buildMatrix builds the matrix include list with the metadata of every target
+func buildMatrix(entries []any) (*matrix, error) {
+	m := &matrix{Include: make([]matrixEntry, 0, len(entries))}
+	for _, entry := range entries {
+		root, ok := entry.(rootEntry)
+		if !ok {
+			return nil, fmt.Errorf("unexpected matrix entry %v", entry)
+		}
+		m.Include = append(m.Include, matrixEntry{rootEntry: root, Slug: slug(root.Path, root.Workspace)})
+	}
+	return m, nil
+}
+
+// writeGitHubActions sets the step outputs and appends a summary of the updated root modules.
+// Files are skipped with a warning if GitHub Actions did not set their environment variables.
+func writeGitHubActions(entries []any, logger *slog.Logger) error {
+	m, err := buildMatrix(entries)
+	if err != nil {
+		return err
+	}
+
+	roots := make([]string, 0)
+	for _, entry := range m.Include {
+		if !slices.Contains(roots, entry.Path) {
+			roots = append(roots, entry.Path)
+		}
+	}
+
+	matrixJSON, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal matrix: %w", err)
+	}
+	rootsJSON, err := json.Marshal(roots)
+	if err != nil {
+		return fmt.Errorf("failed to marshal roots: %w", err)
+	}
+
+	var outputs strings.Builder
+	fmt.Fprintf(&outputs, "has_changes=%t\n", len(roots) > 0)
+	fmt.Fprintf(&outputs, "roots=%s\n", rootsJSON)
+	fmt.Fprintf(&outputs, "count=%d\n", len(roots))
+	fmt.Fprintf(&outputs, "matrix=%s\n", matrixJSON)
+	if err := appendToEnvFile(githubOutputEnv, outputs.String(), logger); err != nil {
+		return err
+	}
+
+	return appendToEnvFile(githubStepSummaryEnv, buildStepSummary(m), logger)
+}
+
+// buildStepSummary renders the matrix as a markdown table for the step summary
+func buildStepSummary(m *matrix) string {
+	var summary strings.Builder
+	summary.WriteString("### Updated Terraform root modules\n\n")
+	if len(m.Include) == 0 {
+		summary.WriteString("No root modules were updated.\n")
+		return summary.String()
+	}
+
+	hasWorkspaces := slices.ContainsFunc(m.Include, func(entry matrixEntry) bool {
+		return entry.Workspace != ""
+	})
+	if hasWorkspaces {
+		summary.WriteString("| Root module | Workspace | Backend | Version |\n")
+		summary.WriteString("|-------------|-----------|---------|---------|\n")
+	} else {
+		summary.WriteString("| Root module | Backend | Version |\n")
+		summary.WriteString("|-------------|---------|---------|\n")
+	}
+
+	for _, entry := range m.Include {
+		backend := "-"
+		switch {
+		case entry.Cloud != nil:
+			backend = "cloud"
+		case entry.Backend != nil:
+			backend = entry.Backend.Type
+		}
+		version := "-"
+		if entry.Version != nil {
+			version = entry.Version.Constraint
+		}
+
+		if hasWorkspaces {
+			workspace := entry.Workspace
+			if workspace == "" {
+				workspace = "-"
+			}
+			fmt.Fprintf(&summary, "| `%s` | %s | %s | %s |\n", entry.Path, workspace, backend, version)
+		} else {
+			fmt.Fprintf(&summary, "| `%s` | %s | %s |\n", entry.Path, backend, version)
+		}
+	}
+
+	return summary.String()
+}
+
+// appendToEnvFile appends content to the file named by the environment variable
+func appendToEnvFile(env, content string, logger *slog.Logger) error {
+	path := os.Getenv(env)
+	if path == "" {
+		logger.Warn("Environment variable for GitHub Actions is not set, skipping", "env", env)
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", env, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", env, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		workspace string
+		expected  string
+	}{
+		{name: "Nested path", path: "environments/organization-1/service-1/dev", expected: "environments-organization-1-service-1-dev"},
+		{name: "Workspace", path: "roots/app", workspace: "prod", expected: "roots-app-prod"},
+		{name: "Special characters", path: "./Roots/My_App", expected: "roots-my-app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slug(tt.path, tt.workspace); got != tt.expected {
+				t.Errorf("Expected slug %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunAnalysis_GitHubActions(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/network/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"roots/network/.terraform-version": "1.6.2",
+		"roots/app/main.tf":                `resource "null_resource" "this" {}`,
+		"roots/db/main.tf":                 `resource "null_resource" "this" {}`,
+	})
+
+	tests := []struct {
+		name            string
+		changedFiles    []string
+		expectedOutputs string
+		expectedSummary string
+	}{
+		{
+			name:         "Updated root modules",
+			changedFiles: []string{"roots/network/main.tf", "roots/app/main.tf"},
+			expectedOutputs: "has_changes=true\n" +
+				`roots=["roots/app","roots/network"]` + "\n" +
+				"count=2\n" +
+				`matrix={"include":[` +
+				`{"path":"roots/app","backend":{"type":"local","config":{"path":"terraform.tfstate"}},"slug":"roots-app"},` +
+				`{"path":"roots/network","backend":{"type":"s3","config":{"bucket":"tfstate","key":"network.tfstate"}},` +
+				`"version":{"tool":"terraform","constraint":"1.6.2","source":"roots/network/.terraform-version"},"slug":"roots-network"}]}` + "\n",
+			expectedSummary: "### Updated Terraform root modules\n\n" +
+				"| Root module | Backend | Version |\n" +
+				"|-------------|---------|---------|\n" +
+				"| `roots/app` | local | - |\n" +
+				"| `roots/network` | s3 | 1.6.2 |\n",
+		},
+		{
+			name:         "No changes",
+			changedFiles: []string{"README.md"},
+			expectedOutputs: "has_changes=false\n" +
+				"roots=[]\n" +
+				"count=0\n" +
+				`matrix={"include":[]}` + "\n",
+			expectedSummary: "### Updated Terraform root modules\n\nNo root modules were updated.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputFile := filepath.Join(t.TempDir(), "output")
+			summaryFile := filepath.Join(t.TempDir(), "summary")
+			t.Setenv(githubOutputEnv, outputFile)
+			t.Setenv(githubStepSummaryEnv, summaryFile)
+
+			args := []string{
+				os.Args[0],
+				"--root-module-dir", filepath.Join(dir, "roots"),
+				"--base-path", dir,
+				"--github-actions",
+				"--log-level", "error",
+			}
+			for _, changedFile := range tt.changedFiles {
+				args = append(args, "--changed-file", filepath.Join(dir, changedFile))
+			}
+
+			var buf bytes.Buffer
+			if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+				t.Fatalf("NewApp().Run() failed: %v", err)
+			}
+
+			outputs, err := os.ReadFile(outputFile)
+			if err != nil {
+				t.Fatalf("Failed to read outputs: %v", err)
+			}
+			if string(outputs) != tt.expectedOutputs {
+				t.Errorf("Expected outputs:\n%s\ngot:\n%s", tt.expectedOutputs, outputs)
+			}
+			summary, err := os.ReadFile(summaryFile)
+			if err != nil {
+				t.Fatalf("Failed to read summary: %v", err)
+			}
+			if string(summary) != tt.expectedSummary {
+				t.Errorf("Expected summary:\n%s\ngot:\n%s", tt.expectedSummary, summary)
+			}
+
+			// The regular output is unchanged
+			if !strings.HasPrefix(buf.String(), "[") {
+				t.Errorf("Expected the default output, got %s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/analyzer"
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+)
+
+// newGraphCommand creates the graph command
+func newGraphCommand(writer io.Writer) *cli.Command {
+	return &cli.Command{
+		Name:                   "graph",
+		Usage:                  "Exports the module graph of the root modules",
+		MutuallyExclusiveFlags: changeSourceFlags(),
+		Flags: slices.Concat([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Graph format (" + strings.Join(modulegraph.Formats(), ", ") + ")",
+				Value: modulegraph.FormatDOT,
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Usage: "Path to write the graph to (default: standard output)",
+			},
+			&cli.BoolFlag{
+				Name:  "highlight",
+				Usage: "Highlight the modules affected by the changes",
+			},
+		}, analysisFlags()),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return runGraph(ctx, cmd, writer)
+		},
+	}
+}
+
+// runGraph builds the module graph of all discovered root modules and renders it
+func runGraph(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	warnings := newWarningRecorder(setupLogger(cmd).Handler())
+	logger := slog.New(warnings)
+
+	format := cmd.String("format")
+	if !slices.Contains(modulegraph.Formats(), format) {
+		return fmt.Errorf("unsupported graph format: %s", format)
+	}
+
+	var a *analysis
+	if cmd.Bool("highlight") {
+		var err error
+		a, err = analyze(cmd, warnings, false, logger)
+		if err != nil {
+			return err
+		}
+	} else {
+		basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+		if err != nil {
+			return err
+		}
+		rootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+		if err != nil {
+			return err
+		}
+		patterns, err := parsePathPatterns(cmd.StringSlice("path-pattern"))
+		if err != nil {
+			return err
+		}
+		filters, err := parseFilters(cmd.StringSlice("filter"))
+		if err != nil {
+			return err
+		}
+		a = &analysis{basePath: basePath, rootModuleDirs: rootModuleDirs, patterns: patterns, filters: filters}
+		a.applyFilters()
+	}
+
+	logger.Info("Building module graph", "rootModules", len(a.rootModuleDirs))
+	graph, err := modulegraph.Build(a.rootModuleDirs, a.basePath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to build module graph: %w", err)
+	}
+
+	if cmd.Bool("highlight") {
+		affected, err := affectedModuleDirs(a, graph, logger)
+		if err != nil {
+			return err
+		}
+		graph.Highlight(affected)
+	}
+
+	data, err := graph.Render(format)
+	if err != nil {
+		return err
+	}
+	return writeOutput(cmd.String("output"), data, writer)
+}
+
+// affectedModuleDirs returns the absolute paths of the targeted root modules and of the local modules
+// in the graph that contain changes, directly or through their own child modules
+func affectedModuleDirs(a *analysis, graph *modulegraph.Graph, logger *slog.Logger) ([]string, error) {
+	affected := make([]string, 0)
+	for _, target := range a.targets {
+		if !slices.Contains(affected, target.Root) {
+			affected = append(affected, target.Root)
+		}
+	}
+
+	changeAnalyzer, err := analyzer.NewAnalyzer(a.rootChangedFiles, a.basePath, logger)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range graph.Nodes {
+		if node.Kind != modulegraph.KindChild {
+			continue
+		}
+		updated, err := changeAnalyzer.IsModuleUpdated(node.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze %s: %w", node.Dir, err)
+		}
+		if updated {
+			affected = append(affected, node.Dir)
+		}
+	}
+	return affected, nil
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunGraph(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "app" {
+  source = "../../modules/app"
+}`,
+		"envs/dev/main.tf": `module "network" {
+  source = "../../modules/network"
+}`,
+		"modules/app/main.tf": `module "db" {
+  source = "./db"
+}`,
+		"modules/app/db/main.tf": `resource "null_resource" "this" {}`,
+		"modules/network/main.tf": `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}`,
+	})
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "DOT",
+			args: []string{},
+			expected: `digraph modules {
+  rankdir=LR;
+  "envs/dev" [shape=box];
+  "envs/prod" [shape=box];
+  "modules/app" [shape=ellipse];
+  "modules/app/db" [shape=ellipse];
+  "modules/network" [shape=ellipse];
+  "terraform-aws-modules/vpc/aws" [shape=component];
+  "envs/dev" -> "modules/network" [label="network\n../../modules/network"];
+  "envs/prod" -> "modules/app" [label="app\n../../modules/app"];
+  "modules/app" -> "modules/app/db" [label="db\n./db"];
+  "modules/network" -> "terraform-aws-modules/vpc/aws" [label="vpc\nterraform-aws-modules/vpc/aws"];
+}
+`,
+		},
+		{
+			name: "Mermaid with highlighted changes",
+			args: []string{"--format", "mermaid", "--highlight", "--changed-file", filepath.Join(dir, "modules", "app", "db", "main.tf")},
+			expected: `flowchart LR
+  n0["envs/dev"]
+  n1["envs/prod"]
+  n2("modules/app")
+  n3("modules/app/db")
+  n4("modules/network")
+  n5[["terraform-aws-modules/vpc/aws"]]
+  n0 -->|"network: ../../modules/network"| n4
+  n1 -->|"app: ../../modules/app"| n2
+  n2 -->|"db: ./db"| n3
+  n4 -->|"vpc: terraform-aws-modules/vpc/aws"| n5
+  classDef highlighted fill:#ffd700
+  class n1,n2,n3 highlighted
+`,
+		},
+		{
+			name: "Filtered root modules",
+			args: []string{"--format", "mermaid", "--path-pattern", "envs/{env}", "--filter", "env=dev"},
+			expected: `flowchart LR
+  n0["envs/dev"]
+  n1("modules/network")
+  n2[["terraform-aws-modules/vpc/aws"]]
+  n0 -->|"network: ../../modules/network"| n1
+  n1 -->|"vpc: terraform-aws-modules/vpc/aws"| n2
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := append([]string{os.Args[0], "graph",
+				"--root-module-dir", filepath.Join(dir, "envs"),
+				"--base-path", dir,
+				"--log-level", "error",
+			}, tt.args...)
+
+			if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected output:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+)
+
+// impactResult is the JSON output of the impact command
+type impactResult struct {
+	Roots []impactRoot `json:"roots"`
+	Count int          `json:"count"`
+}
+
+// impactRoot is a root module consuming one of the given modules
+type impactRoot struct {
+	Path string   `json:"path"`
+	Via  []string `json:"via"` // Modules from the root module to the consumed module
+}
+
+// newImpactCommand creates the impact command
+func newImpactCommand(writer io.Writer) *cli.Command {
+	return &cli.Command{
+		Name:      "impact",
+		Usage:     "Lists the root modules consuming the given modules",
+		ArgsUsage: "<path>...",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format (text, json)",
+				Value: reportFormatText,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return runImpact(ctx, cmd, writer)
+		},
+	}
+}
+
+// runImpact walks the module graph backwards from the given module directories or files
+func runImpact(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	format := cmd.String("format")
+	if err := validateReportFormat(format); err != nil {
+		return err
+	}
+	if cmd.Args().Len() == 0 {
+		return errors.New("impact requires at least one module directory or file")
+	}
+
+	moduleDirs := make([]string, 0, cmd.Args().Len())
+	for _, path := range cmd.Args().Slice() {
+		moduleDir, err := resolveModuleDir(path)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(moduleDirs, moduleDir) {
+			moduleDirs = append(moduleDirs, moduleDir)
+		}
+	}
+
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+	rootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+
+	graph, err := modulegraph.Build(rootModuleDirs, basePath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to build module graph: %w", err)
+	}
+	ids := make([]string, 0, len(moduleDirs))
+	for _, moduleDir := range moduleDirs {
+		id, ok := graph.NodeID(moduleDir)
+		if !ok {
+			logger.Warn("Module is not used by any root module", "module", moduleDir)
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	consumers := graph.Consumers(ids)
+	logger.Info("Found root modules consuming the modules", "count", len(consumers))
+
+	result := impactResult{Roots: make([]impactRoot, 0, len(consumers)), Count: len(consumers)}
+	for _, consumer := range consumers {
+		result.Roots = append(result.Roots, impactRoot{Path: consumer.Root, Via: consumer.Path})
+	}
+
+	return writeReport(writer, format, result, func(report *strings.Builder) {
+		for _, root := range result.Roots {
+			fmt.Fprintf(report, "%s\n  via %s\n", root.Path, strings.Join(root.Via, " -> "))
+		}
+		fmt.Fprintf(report, "%s affected\n", pluralize(result.Count, "root module"))
+	})
+}
+
+// resolveModuleDir returns the absolute path of the module directory, or of the directory containing the file
+func resolveModuleDir(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path for %s: %w", path, err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to access %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return absPath, nil
+	}
+	return filepath.Dir(absPath), nil
+}
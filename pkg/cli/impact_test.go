@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunImpact(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "service" {
+  source = "../../modules/service"
+}`,
+		"envs/dev/main.tf": `module "common" {
+  source = "../../modules/common"
+}`,
+		"envs/sandbox/main.tf": `resource "null_resource" "this" {}`,
+		"modules/service/main.tf": `module "common" {
+  source = "../common"
+}`,
+		"modules/common/main.tf":      `resource "null_resource" "this" {}`,
+		"modules/unused/main.tf":      `resource "null_resource" "this" {}`,
+		"modules/common/variables.tf": `variable "name" {}`,
+	})
+
+	tests := []struct {
+		name          string
+		args          []string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name: "Module directory",
+			args: []string{filepath.Join(dir, "modules", "common")},
+			expected: `envs/dev
+  via envs/dev -> modules/common
+envs/prod
+  via envs/prod -> modules/service -> modules/common
+2 root modules affected
+`,
+		},
+		{
+			name: "File",
+			args: []string{filepath.Join(dir, "modules", "service", "main.tf")},
+			expected: `envs/prod
+  via envs/prod -> modules/service
+1 root module affected
+`,
+		},
+		{
+			name:     "File as JSON",
+			args:     []string{"--format", "json", filepath.Join(dir, "modules", "service", "main.tf")},
+			expected: `{"roots":[{"path":"envs/prod","via":["envs/prod","modules/service"]}],"count":1}` + "\n",
+		},
+		{
+			name:     "Unused module",
+			args:     []string{filepath.Join(dir, "modules", "unused")},
+			expected: "0 root modules affected\n",
+		},
+		{
+			name:          "Missing path",
+			args:          []string{filepath.Join(dir, "modules", "missing")},
+			expectedError: true,
+		},
+		{
+			name:          "No path",
+			args:          []string{},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := append([]string{os.Args[0], "impact",
+				"--root-module-dir", filepath.Join(dir, "envs"),
+				"--base-path", dir,
+				"--log-level", "error",
+			}, tt.args...)
+
+			err := NewApp(&buf).Run(context.Background(), args)
+			if tt.expectedError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected output:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/analyzer"
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+)
+
+// moduleInventory is the JSON output of the list modules command
+type moduleInventory struct {
+	Local  []localModule  `json:"local"`
+	Remote []remoteModule `json:"remote"`
+}
+
+// localModule is a local child module and the number of root modules consuming it
+type localModule struct {
+	Path      string `json:"path"`
+	Consumers int    `json:"consumers"`
+}
+
+// remoteModule is a remote module source and the root modules referencing it
+type remoteModule struct {
+	Source string   `json:"source"`
+	Roots  []string `json:"roots"`
+}
+
+// newListCommand creates the list command and its subcommands
+func newListCommand(writer io.Writer) *cli.Command {
+	formatFlag := func() cli.Flag {
+		return &cli.StringFlag{
+			Name:  "format",
+			Usage: "Output format (text, json)",
+			Value: reportFormatText,
+		}
+	}
+
+	return &cli.Command{
+		Name:  "list",
+		Usage: "Lists the discovered modules",
+		Commands: []*cli.Command{
+			{
+				Name:  "roots",
+				Usage: "Lists the discovered root modules",
+				Flags: []cli.Flag{formatFlag()},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runListRoots(ctx, cmd, writer)
+				},
+			},
+			{
+				Name:  "modules",
+				Usage: "Lists the local and remote modules called by the root modules",
+				Flags: []cli.Flag{formatFlag()},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runListModules(ctx, cmd, writer)
+				},
+			},
+		},
+	}
+}
+
+// runListRoots prints the root modules found in the root module directories
+func runListRoots(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	format := cmd.String("format")
+	if err := validateReportFormat(format); err != nil {
+		return err
+	}
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+	rootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+
+	roots, err := analyzer.ConvertToRelativePaths(basePath, rootModuleDirs, logger)
+	if err != nil {
+		return fmt.Errorf("failed to convert paths: %w", err)
+	}
+	slices.Sort(roots)
+	roots = slices.Compact(roots)
+
+	return writeReport(writer, format, roots, func(report *strings.Builder) {
+		for _, root := range roots {
+			fmt.Fprintln(report, root)
+		}
+	})
+}
+
+// runListModules prints every local child module with its number of consumers and every remote
+// module source with the root modules referencing it, directly or through local modules
+func runListModules(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	format := cmd.String("format")
+	if err := validateReportFormat(format); err != nil {
+		return err
+	}
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+	rootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+
+	graph, err := modulegraph.Build(rootModuleDirs, basePath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to build module graph: %w", err)
+	}
+
+	inventory := moduleInventory{Local: make([]localModule, 0), Remote: make([]remoteModule, 0)}
+	for _, node := range graph.Nodes {
+		consumers := graph.Consumers([]string{node.ID})
+		switch node.Kind {
+		case modulegraph.KindChild:
+			inventory.Local = append(inventory.Local, localModule{Path: node.ID, Consumers: len(consumers)})
+		case modulegraph.KindRemote:
+			roots := make([]string, 0, len(consumers))
+			for _, consumer := range consumers {
+				roots = append(roots, consumer.Root)
+			}
+			inventory.Remote = append(inventory.Remote, remoteModule{Source: node.ID, Roots: roots})
+		}
+	}
+
+	return writeReport(writer, format, inventory, func(report *strings.Builder) {
+		report.WriteString("Local modules:\n")
+		for _, module := range inventory.Local {
+			fmt.Fprintf(report, "  %s (%s)\n", module.Path, pluralize(module.Consumers, "root module"))
+		}
+		report.WriteString("Remote modules:\n")
+		for _, module := range inventory.Remote {
+			fmt.Fprintf(report, "  %s\n", module.Source)
+			for _, root := range module.Roots {
+				fmt.Fprintf(report, "    - %s\n", root)
+			}
+		}
+	})
+}
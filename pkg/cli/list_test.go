@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunList(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "service" {
+  source = "../../modules/service"
+}
+module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}`,
+		"envs/dev/main.tf": `module "common" {
+  source = "../../modules/common"
+}`,
+		"modules/service/main.tf": `module "common" {
+  source = "../common"
+}
+module "bucket" {
+  source = "git::https://example.com/bucket.git?ref=v1.0.0"
+}`,
+		"modules/common/main.tf": `module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}`,
+	})
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "Roots",
+			args:     []string{"roots"},
+			expected: "envs/dev\nenvs/prod\n",
+		},
+		{
+			name:     "Roots as JSON",
+			args:     []string{"roots", "--format", "json"},
+			expected: `["envs/dev","envs/prod"]` + "\n",
+		},
+		{
+			name: "Modules",
+			args: []string{"modules"},
+			expected: `Local modules:
+  modules/common (2 root modules)
+  modules/service (1 root module)
+Remote modules:
+  git::https://example.com/bucket.git
+    - envs/prod
+  terraform-aws-modules/vpc/aws
+    - envs/dev
+    - envs/prod
+`,
+		},
+		{
+			name: "Modules as JSON",
+			args: []string{"modules", "--format", "json"},
+			expected: `{"local":[{"path":"modules/common","consumers":2},{"path":"modules/service","consumers":1}],` +
+				`"remote":[{"source":"git::https://example.com/bucket.git","roots":["envs/prod"]},` +
+				`{"source":"terraform-aws-modules/vpc/aws","roots":["envs/dev","envs/prod"]}]}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := append([]string{os.Args[0], "list"}, tt.args...)
+			args = append(args, "--root-module-dir", filepath.Join(dir, "envs"), "--base-path", dir, "--log-level", "error")
+
+			if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected output:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// markdownMarker is a hidden comment identifying the report, so that a bot can find and update its comment
+const markdownMarker = "<!-- tf-mod-watcher:report -->"
+
+// markdownReport is a report rendered for a pull request comment
+type markdownReport string
+
+// buildMarkdown renders the json-v2 result as a pull request comment. The output only depends on the
+// analysis, so posting it again for the same changes produces the same comment.
+func buildMarkdown(a *analysis, options outputOptions, logger *slog.Logger) (markdownReport, error) {
+	result, err := buildResultV2(a, options, logger)
+	if err != nil {
+		return "", err
+	}
+
+	affected := make([]rootResult, 0)
+	deleted := make([]rootResult, 0)
+	counts := make(map[string]int)
+	for _, root := range result.Roots {
+		counts[root.Status]++
+		switch root.Status {
+		case statusUpdated, statusDependent:
+			affected = append(affected, root)
+		case statusDeleted:
+			deleted = append(deleted, root)
+		}
+	}
+
+	var report strings.Builder
+	report.WriteString(markdownMarker + "\n")
+	report.WriteString("### Terraform root modules\n\n")
+	fmt.Fprintf(&report, "**%d affected** (%d updated, %d dependent), %d deleted, %d unchanged\n",
+		len(affected), counts[statusUpdated], counts[statusDependent], counts[statusDeleted], counts[statusUnchanged])
+	if result.Commits != nil {
+		fmt.Fprintf(&report, "\nCompared `%s` (%s) with `%s` (%s)\n",
+			result.Commits.Before.Ref, shortHash(result.Commits.Before.Hash),
+			result.Commits.After.Ref, shortHash(result.Commits.After.Hash))
+	}
+
+	if len(affected) == 0 {
+		report.WriteString("\nNo root modules are affected.\n")
+	}
+
+	// Roots are grouped by the directory below the one shared by all of them, so that a base path
+	// above a single top-level directory does not put every root into one group
+	paths := make([]string, 0, len(result.Roots))
+	for _, root := range result.Roots {
+		paths = append(paths, root.Path)
+	}
+	shared := commonDir(paths)
+
+	for _, group := range groupNames(affected, shared) {
+		fmt.Fprintf(&report, "\n#### `%s`\n\n", group)
+		report.WriteString("| Root module | Status | Workspaces | Change kinds | Changed files |\n")
+		report.WriteString("|-------------|--------|------------|--------------|---------------|\n")
+		for _, root := range affected {
+			if groupName(shared, root.Path) != group {
+				continue
+			}
+			workspaces := "-"
+			if len(root.Workspaces) > 0 {
+				workspaces = strings.Join(root.Workspaces, ", ")
+			}
+			kinds := "-"
+			if len(root.ChangeKinds) > 0 {
+				kinds = strings.Join(root.ChangeKinds, ", ")
+			}
+			fmt.Fprintf(&report, "| `%s` | %s | %s | %s | %d |\n", root.Path, root.Status, workspaces, kinds, len(root.ChangedFiles))
+		}
+
+		for _, root := range affected {
+			if groupName(shared, root.Path) != group {
+				continue
+			}
+			fmt.Fprintf(&report, "\n<details>\n<summary><code>%s</code></summary>\n\n", root.Path)
+			report.WriteString("**Why**\n\n")
+			for _, explanation := range explain(a, result, root) {
+				fmt.Fprintf(&report, "- %s\n", explanation)
+			}
+			if len(root.ChangedFiles) > 0 {
+				report.WriteString("\n**Changed files**\n\n")
+				for _, file := range root.ChangedFiles {
+					fmt.Fprintf(&report, "- `%s`\n", file)
+				}
+			}
+			report.WriteString("\n</details>\n")
+		}
+	}
+
+	if len(deleted) > 0 {
+		report.WriteString("\n#### Deleted root modules\n\n")
+		for _, root := range deleted {
+			fmt.Fprintf(&report, "- `%s`\n", root.Path)
+		}
+	}
+
+	return markdownReport(report.String()), nil
+}
+
+// explain returns why the root module is affected, as chains from the root module to the change
+func explain(a *analysis, result *resultV2, root rootResult) []string {
+	explanations := make([]string, 0)
+
+	if root.Status == statusDependent {
+		affected := make([]string, 0)
+		pathOf := make(map[string]string)
+		for _, other := range result.Roots {
+			if other.Status == statusUpdated || other.Status == statusDependent {
+				affected = append(affected, other.AbsolutePath)
+				pathOf[other.AbsolutePath] = other.Path
+			}
+		}
+		if a.graph != nil {
+			for _, dependency := range a.graph.SelectedDependencies(root.AbsolutePath, affected) {
+				explanations = append(explanations, fmt.Sprintf("`%s` → reads the state of `%s`", root.Path, pathOf[dependency]))
+			}
+		}
+		return explanations
+	}
+
+	for _, chain := range root.Chains {
+		elements := strings.Split(chain, " -> ")
+		for i, element := range elements {
+			elements[i] = "`" + element + "`"
+		}
+		explanations = append(explanations, strings.Join(elements, " → "))
+	}
+	return explanations
+}
+
+// groupNames returns the distinct groups of the root modules in order
+func groupNames(roots []rootResult, shared string) []string {
+	groups := make([]string, 0)
+	for _, root := range roots {
+		group := groupName(shared, root.Path)
+		if !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// commonDir returns the longest directory shared by all the given relative paths, empty if there is none
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	shared := strings.Split(filepath.ToSlash(paths[0]), "/")
+	for _, path := range paths[1:] {
+		elements := strings.Split(filepath.ToSlash(path), "/")
+		n := 0
+		for n < len(shared) && n < len(elements) && shared[n] == elements[n] {
+			n++
+		}
+		shared = shared[:n]
+	}
+	return strings.Join(shared, "/")
+}
+
+// groupName returns the directory one level below the shared directory that contains the path
+func groupName(shared, path string) string {
+	path = filepath.ToSlash(path)
+	rest := path
+	if shared != "" {
+		rest = strings.TrimPrefix(strings.TrimPrefix(path, shared), "/")
+		if rest == "" {
+			return path
+		}
+	}
+	next, _, _ := strings.Cut(rest, "/")
+	if shared == "" {
+		return next
+	}
+	return shared + "/" + next
+}
+
+// shortHash abbreviates a commit hash like git does
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunAnalysis_Markdown(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/network/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"envs/app/main.tf": `data "terraform_remote_state" "network" {
+  backend = "s3"
+  config = {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"envs/app/env/prod.tfvars": `name = "prod"`,
+		"envs/db/main.tf":          `resource "null_resource" "this" {}`,
+		"sandbox/main.tf": `module "service" {
+  source = "../modules/service"
+}`,
+		"modules/service/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	run := func(changedFiles ...string) string {
+		t.Helper()
+		args := []string{
+			os.Args[0],
+			"--root-module-dir", filepath.Join(dir, "envs"),
+			"--root-module-dir", filepath.Join(dir, "sandbox"),
+			"--base-path", dir,
+			"--output-format", "markdown",
+			"--include-dependents",
+			"--workspace-var-file", "env/{workspace}.tfvars",
+			"--log-level", "error",
+		}
+		for _, changedFile := range changedFiles {
+			args = append(args, "--changed-file", filepath.Join(dir, changedFile))
+		}
+
+		var buf bytes.Buffer
+		if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+			t.Fatalf("NewApp().Run() failed: %v", err)
+		}
+		return buf.String()
+	}
+
+	expected := "<!-- tf-mod-watcher:report -->\n" +
+		"### Terraform root modules\n" +
+		"\n" +
+		"**3 affected** (2 updated, 1 dependent), 1 deleted, 1 unchanged\n" +
+		"\n" +
+		"#### `envs`\n" +
+		"\n" +
+		"| Root module | Status | Workspaces | Change kinds | Changed files |\n" +
+		"|-------------|--------|------------|--------------|---------------|\n" +
+		"| `envs/app` | dependent | prod | - | 0 |\n" +
+		"| `envs/network` | updated | - | config | 1 |\n" +
+		"\n" +
+		"<details>\n" +
+		"<summary><code>envs/app</code></summary>\n" +
+		"\n" +
+		"**Why**\n" +
+		"\n" +
+		"- `envs/app` → reads the state of `envs/network`\n" +
+		"\n" +
+		"</details>\n" +
+		"\n" +
+		"<details>\n" +
+		"<summary><code>envs/network</code></summary>\n" +
+		"\n" +
+		"**Why**\n" +
+		"\n" +
+		"- `envs/network` → `main.tf`\n" +
+		"\n" +
+		"**Changed files**\n" +
+		"\n" +
+		"- `envs/network/main.tf`\n" +
+		"\n" +
+		"</details>\n" +
+		"\n" +
+		"#### `sandbox`\n" +
+		"\n" +
+		"| Root module | Status | Workspaces | Change kinds | Changed files |\n" +
+		"|-------------|--------|------------|--------------|---------------|\n" +
+		"| `sandbox` | updated | - | config | 1 |\n" +
+		"\n" +
+		"<details>\n" +
+		"<summary><code>sandbox</code></summary>\n" +
+		"\n" +
+		"**Why**\n" +
+		"\n" +
+		"- `sandbox` → `modules/service` → `main.tf`\n" +
+		"\n" +
+		"**Changed files**\n" +
+		"\n" +
+		"- `modules/service/main.tf`\n" +
+		"\n" +
+		"</details>\n" +
+		"\n" +
+		"#### Deleted root modules\n" +
+		"\n" +
+		"- `envs/legacy`\n"
+
+	changedFiles := []string{"envs/network/main.tf", "modules/service/main.tf", "envs/legacy/main.tf"}
+	output := run(changedFiles...)
+	if output != expected {
+		t.Errorf("Expected report:\n%s\ngot:\n%s", expected, output)
+	}
+
+	// The report is posted again for the same changes, so it must not change between runs
+	reversed := []string{changedFiles[2], changedFiles[1], changedFiles[0]}
+	if again := run(reversed...); again != output {
+		t.Errorf("Expected the same report for the same changes, got:\n%s", again)
+	}
+
+	empty := run("README.md")
+	expectedEmpty := "<!-- tf-mod-watcher:report -->\n" +
+		"### Terraform root modules\n" +
+		"\n" +
+		"**0 affected** (0 updated, 0 dependent), 0 deleted, 4 unchanged\n" +
+		"\n" +
+		"No root modules are affected.\n"
+	if empty != expectedEmpty {
+		t.Errorf("Expected report:\n%s\ngot:\n%s", expectedEmpty, empty)
+	}
+}
+
+func TestMarkdownGroups(t *testing.T) {
+	tests := []struct {
+		name     string
+		paths    []string
+		expected []string
+	}{
+		{
+			name:     "Top-level directories",
+			paths:    []string{"envs/app", "envs/network", "sandbox"},
+			expected: []string{"envs", "envs", "sandbox"},
+		},
+		{
+			name:     "Single top-level directory",
+			paths:    []string{"terraform/live/dev", "terraform/live/prod", "terraform/shared"},
+			expected: []string{"terraform/live", "terraform/live", "terraform/shared"},
+		},
+		{
+			name:     "Root in the shared directory",
+			paths:    []string{"terraform", "terraform/live/dev"},
+			expected: []string{"terraform", "terraform/live"},
+		},
+		{
+			name:     "Single root",
+			paths:    []string{"terraform/live/dev"},
+			expected: []string{"terraform/live/dev"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shared := commonDir(tt.paths)
+			groups := make([]string, 0, len(tt.paths))
+			for _, path := range tt.paths {
+				groups = append(groups, groupName(shared, path))
+			}
+			if !slices.Equal(groups, tt.expected) {
+				t.Errorf("Expected groups %v, got %v", tt.expected, groups)
+			}
+		})
+	}
+}
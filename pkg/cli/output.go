@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/analyzer"
+	"github.com/hurack3034217/tf-mod-watcher/internal/attribute"
+	"github.com/hurack3034217/tf-mod-watcher/internal/metadata"
+	"github.com/hurack3034217/tf-mod-watcher/internal/workspace"
+)
+
+// Output formats of the inventory subcommands
+const (
+	reportFormatText = "text"
+	reportFormatJSON = "json"
+)
+
+// rootEntry is a root module with its workspace and metadata in the output
+type rootEntry struct {
+	Path       string            `json:"path"`
+	Workspace  string            `json:"workspace,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Chains     []string          `json:"chains,omitempty"`
+	*metadata.Root
+}
+
+// outputOptions controls how targets are rendered in the output
+type outputOptions struct {
+	basePath        string
+	includeMetadata bool
+	workspaces      bool                // Whether workspace conventions are configured
+	patterns        []attribute.Pattern // Patterns deriving attributes from root module paths
+	chains          map[string][]string // Change chains keyed by the absolute path of the root module, nil unless explained
+}
+
+// buildEntries converts targets to output entries sorted by path and workspace.
+// Entries are relative paths, or rootEntry values when metadata, workspaces, attributes or chains are included.
+func buildEntries(targets []workspace.Target, options outputOptions, logger *slog.Logger) ([]any, error) {
+	moduleDirs := make([]string, 0, len(targets))
+	for _, target := range targets {
+		moduleDirs = append(moduleDirs, target.Root)
+	}
+	relPaths, err := analyzer.ConvertToRelativePaths(options.basePath, moduleDirs, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert paths: %w", err)
+	}
+
+	order := make([]int, len(targets))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Or(cmp.Compare(relPaths[a], relPaths[b]), cmp.Compare(targets[a].Workspace, targets[b].Workspace))
+	})
+
+	// Workspaces of the same root module share its metadata
+	rootMetadata := make(map[string]*metadata.Root)
+	entries := make([]any, 0, len(targets))
+	for _, i := range order {
+		if !options.includeMetadata && !options.workspaces && len(options.patterns) == 0 && options.chains == nil {
+			entries = append(entries, relPaths[i])
+			continue
+		}
+
+		entry := rootEntry{
+			Path:       relPaths[i],
+			Workspace:  targets[i].Workspace,
+			Attributes: deriveAttributes(options.basePath, targets[i].Root, options.patterns),
+			Chains:     options.chains[targets[i].Root],
+		}
+		if options.includeMetadata {
+			if _, exists := rootMetadata[targets[i].Root]; !exists {
+				collected, err := metadata.Collect(targets[i].Root, options.basePath)
+				if err != nil {
+					return nil, fmt.Errorf("failed to collect metadata of %s: %w", targets[i].Root, err)
+				}
+				rootMetadata[targets[i].Root] = collected
+			}
+			entry.Root = rootMetadata[targets[i].Root]
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// writeOutput writes data to the file at path, or to writer if path is empty.
+// The file is replaced atomically, so readers never see partial output.
+func writeOutput(path string, data []byte, writer io.Writer) error {
+	if path == "" {
+		if _, err := writer.Write(data); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	// The temporary file must be on the same file system for the rename to be atomic
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %w", path, err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// marshalOutput encodes the value as JSON without escaping HTML characters, so that chains keep their "->"
+func marshalOutput(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// pluralize returns the count followed by the noun, which gets an s unless the count is one
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// validateReportFormat checks the format flag of the inventory subcommands
+func validateReportFormat(format string) error {
+	if format != reportFormatText && format != reportFormatJSON {
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+	return nil
+}
+
+// writeReport writes the value as JSON or the text rendered by text, depending on the format
+func writeReport(writer io.Writer, format string, value any, text func(report *strings.Builder)) error {
+	var output []byte
+	if format == reportFormatJSON {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		output = append(data, '\n')
+	} else {
+		var report strings.Builder
+		text(&report)
+		output = []byte(report.String())
+	}
+
+	if _, err := writer.Write(output); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
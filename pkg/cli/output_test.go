@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+	"github.com/hurack3034217/tf-mod-watcher/internal/workspace"
+)
+
+func TestBuildEntries(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"b/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+  }
+}`,
+		"a/main.tf": `resource "null_resource" "this" {}`,
+	})
+	targets := []workspace.Target{{Root: filepath.Join(dir, "b")}, {Root: filepath.Join(dir, "a")}}
+
+	entries, err := buildEntries(targets, outputOptions{basePath: dir}, getTestLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0] != "a" || entries[1] != "b" {
+		t.Errorf("Expected sorted relative paths, got %v", entries)
+	}
+
+	entries, err = buildEntries(targets, outputOptions{basePath: dir, includeMetadata: true}, getTestLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %v", entries)
+	}
+	entry, ok := entries[1].(rootEntry)
+	if !ok {
+		t.Fatalf("Expected rootEntry, got %T", entries[1])
+	}
+	if entry.Path != "b" || entry.Backend == nil || entry.Backend.Type != "s3" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	targets = []workspace.Target{{Root: filepath.Join(dir, "a"), Workspace: "prod"}, {Root: filepath.Join(dir, "a"), Workspace: "dev"}}
+	entries, err = buildEntries(targets, outputOptions{basePath: dir, workspaces: true}, getTestLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []any{rootEntry{Path: "a", Workspace: "dev"}, rootEntry{Path: "a", Workspace: "prod"}}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %v, got %v", expected, entries)
+	}
+}
+
+func TestRunAnalysis_IncludeMetadata(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/network/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+  }
+}`,
+		"roots/service/main.tf": `terraform {
+  cloud {
+    organization = "example"
+    workspaces {
+      name = "service"
+    }
+  }
+}`,
+	})
+
+	var buf bytes.Buffer
+	err := NewApp(&buf).Run(context.Background(), []string{
+		os.Args[0],
+		"--root-module-dir", filepath.Join(dir, "roots"),
+		"--base-path", dir,
+		"--changed-file", filepath.Join(dir, "roots", "network", "main.tf"),
+		"--changed-file", filepath.Join(dir, "roots", "service", "main.tf"),
+		"--include-metadata",
+		"--log-level", "error",
+	})
+	if err != nil {
+		t.Fatalf("NewApp().Run() failed: %v", err)
+	}
+
+	expected := `[{"path":"roots/network","backend":{"type":"s3","config":{"bucket":"tfstate","key":"unknown"}}},` +
+		`{"path":"roots/service","cloud":{"organization":"example","hostname":"app.terraform.io","workspaces":{"name":"service"}}}]`
+	if buf.String() != expected {
+		t.Errorf("Expected output %s, got %s", expected, buf.String())
+	}
+}
+
+func TestRunAnalysis_WorkspaceVarFile(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/app/main.tf":            `resource "null_resource" "this" {}`,
+		"roots/app/env/dev.tfvars":     `size = "small"`,
+		"roots/app/env/prod.tfvars":    `size = "large"`,
+		"roots/db/main.tf":             `resource "null_resource" "this" {}`,
+		"roots/db/env/prod.tfvars":     `size = "large"`,
+		"roots/plain/main.tf":          `resource "null_resource" "this" {}`,
+		"roots/plain/terraform.tfvars": `size = "small"`,
+	})
+
+	tests := []struct {
+		name         string
+		changedFiles []string
+		expected     string
+	}{
+		{
+			name:         "Var file change triggers only its workspace",
+			changedFiles: []string{"roots/app/env/prod.tfvars"},
+			expected:     `[{"path":"roots/app","workspace":"prod"}]`,
+		},
+		{
+			name:         "Configuration change triggers all workspaces",
+			changedFiles: []string{"roots/app/main.tf", "roots/db/env/prod.tfvars"},
+			expected:     `[{"path":"roots/app","workspace":"dev"},{"path":"roots/app","workspace":"prod"},{"path":"roots/db","workspace":"prod"}]`,
+		},
+		{
+			name:         "Deleted var file still triggers its workspace",
+			changedFiles: []string{"roots/db/env/staging.tfvars"},
+			expected:     `[{"path":"roots/db","workspace":"staging"}]`,
+		},
+		{
+			name:         "Root module without workspaces",
+			changedFiles: []string{"roots/plain/terraform.tfvars"},
+			expected:     `[{"path":"roots/plain"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{
+				os.Args[0],
+				"--root-module-dir", filepath.Join(dir, "roots"),
+				"--base-path", dir,
+				"--workspace-var-file", "env/{workspace}.tfvars",
+				"--log-level", "error",
+			}
+			for _, changedFile := range tt.changedFiles {
+				args = append(args, "--changed-file", filepath.Join(dir, changedFile))
+			}
+
+			var buf bytes.Buffer
+			if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+				t.Fatalf("NewApp().Run() failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected output %s, got %s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestRunAnalysis_ExitCodeAndOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/app/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	tests := []struct {
+		name           string
+		changedFile    string
+		args           []string
+		expectChanges  bool
+		expectedStdout string
+		expectedFile   string
+	}{
+		{
+			name:           "Changes",
+			changedFile:    "roots/app/main.tf",
+			args:           []string{"--exit-code"},
+			expectChanges:  true,
+			expectedStdout: `["roots/app"]`,
+		},
+		{
+			name:           "No changes",
+			changedFile:    "README.md",
+			args:           []string{"--exit-code"},
+			expectedStdout: `[]`,
+		},
+		{
+			name:          "Quiet",
+			changedFile:   "roots/app/main.tf",
+			args:          []string{"--exit-code", "--quiet"},
+			expectChanges: true,
+		},
+		{
+			name:         "Output file",
+			changedFile:  "roots/app/main.tf",
+			args:         []string{"--quiet", "--output-file", filepath.Join(dir, "out", "roots.json")},
+			expectedFile: `["roots/app"]`,
+		},
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "out"), 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	// An existing file is replaced as a whole
+	testutil.WriteFiles(t, dir, map[string]string{"out/roots.json": `["stale", "output", "that", "is", "longer"]`})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewApp(&buf).Run(context.Background(), append([]string{
+				os.Args[0],
+				"--root-module-dir", filepath.Join(dir, "roots"),
+				"--base-path", dir,
+				"--changed-file", filepath.Join(dir, tt.changedFile),
+				"--log-level", "error",
+			}, tt.args...))
+			if tt.expectChanges {
+				if !errors.Is(err, ErrChanges) {
+					t.Errorf("Expected ErrChanges, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("NewApp().Run() failed: %v", err)
+			}
+
+			if buf.String() != tt.expectedStdout {
+				t.Errorf("Expected standard output %q, got %q", tt.expectedStdout, buf.String())
+			}
+			if tt.expectedFile != "" {
+				data, err := os.ReadFile(filepath.Join(dir, "out", "roots.json"))
+				if err != nil {
+					t.Fatalf("Failed to read output file: %v", err)
+				}
+				if string(data) != tt.expectedFile {
+					t.Errorf("Expected output file %s, got %s", tt.expectedFile, data)
+				}
+				entries, err := os.ReadDir(filepath.Join(dir, "out"))
+				if err != nil {
+					t.Fatalf("Failed to read output directory: %v", err)
+				}
+				if len(entries) != 1 {
+					t.Errorf("Expected temporary files to be removed, got %d files", len(entries))
+				}
+			}
+		})
+	}
+}
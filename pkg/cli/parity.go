@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+	"github.com/hurack3034217/tf-mod-watcher/internal/parity"
+)
+
+// parityResult is the JSON output of the parity command
+type parityResult struct {
+	Groups []parityGroup `json:"groups"`
+}
+
+// parityGroup is a set of sibling root modules that only differ in the compared attribute
+type parityGroup struct {
+	Attributes  map[string]string  `json:"attributes"`
+	Roots       map[string]string  `json:"roots"` // Paths of the root modules, keyed by the compared attribute
+	Differences []parityDifference `json:"differences"`
+}
+
+// parityDifference is a module that the root modules of a group do not use alike
+type parityDifference struct {
+	Module string            `json:"module"`
+	Values map[string]string `json:"values"` // Version, "present" or "missing", keyed by the compared attribute
+}
+
+// newParityCommand creates the parity command
+func newParityCommand(writer io.Writer) *cli.Command {
+	return &cli.Command{
+		Name:  "parity",
+		Usage: "Compares the modules used by sibling root modules, e.g. the environments of a service",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:     "path-pattern",
+				Usage:    "Pattern deriving attributes from root module paths, e.g. environments/{org}/{service}/{env} (can be specified multiple times)",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "across",
+				Usage:    "Attribute that differs between sibling root modules, e.g. env",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format (text, json)",
+				Value: reportFormatText,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return runParity(ctx, cmd, writer)
+		},
+	}
+}
+
+// runParity groups the root modules by their attributes other than the compared one and reports
+// the modules that the root modules of a group do not use alike
+func runParity(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	format := cmd.String("format")
+	if err := validateReportFormat(format); err != nil {
+		return err
+	}
+	patterns, err := parsePathPatterns(cmd.StringSlice("path-pattern"))
+	if err != nil {
+		return err
+	}
+	across := cmd.String("across")
+
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+	rootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+	graph, err := modulegraph.Build(rootModuleDirs, basePath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to build module graph: %w", err)
+	}
+
+	groups := make(map[string]*parityGroup)
+	members := make(map[string][]parity.Member)
+	for _, node := range graph.Nodes {
+		if node.Kind != modulegraph.KindRoot {
+			continue
+		}
+		attributes := deriveAttributes(basePath, node.Dir, patterns)
+		name, exists := attributes[across]
+		if !exists {
+			logger.Debug("Root module does not have the compared attribute", "root", node.ID, "attribute", across)
+			continue
+		}
+		delete(attributes, across)
+
+		label := formatAttributes(attributes)
+		if groups[label] == nil {
+			groups[label] = &parityGroup{Attributes: attributes, Roots: make(map[string]string)}
+		}
+		if other, exists := groups[label].Roots[name]; exists {
+			return fmt.Errorf("root modules %s and %s have the same attributes and %s=%s", other, node.ID, across, name)
+		}
+		groups[label].Roots[name] = node.ID
+		members[label] = append(members[label], parity.Member{Name: name, Root: node.ID})
+	}
+
+	result := parityResult{Groups: make([]parityGroup, 0, len(groups))}
+	for _, label := range slices.Sorted(maps.Keys(groups)) {
+		if len(members[label]) < 2 {
+			continue
+		}
+		group := groups[label]
+		group.Differences = make([]parityDifference, 0)
+		for _, difference := range parity.Compare(graph, members[label]) {
+			group.Differences = append(group.Differences, parityDifference{Module: difference.Module, Values: difference.Values})
+		}
+		result.Groups = append(result.Groups, *group)
+	}
+	logger.Info("Compared sibling root modules", "groups", len(result.Groups))
+
+	return writeReport(writer, format, result, func(report *strings.Builder) {
+		differing := 0
+		for _, group := range result.Groups {
+			if len(group.Differences) == 0 {
+				continue
+			}
+			differing++
+			names := slices.Sorted(maps.Keys(group.Roots))
+			fmt.Fprintf(report, "%s (%s)\n", formatAttributes(group.Attributes), strings.Join(names, ", "))
+			for _, difference := range group.Differences {
+				values := make([]string, 0, len(names))
+				for _, name := range names {
+					values = append(values, name+"="+difference.Values[name])
+				}
+				fmt.Fprintf(report, "  %s: %s\n", difference.Module, strings.Join(values, ", "))
+			}
+		}
+		fmt.Fprintf(report, "%d of %d groups differ\n", differing, len(result.Groups))
+	})
+}
+
+// formatAttributes renders attributes as sorted key=value pairs
+func formatAttributes(attributes map[string]string) string {
+	pairs := make([]string, 0, len(attributes))
+	for _, key := range slices.Sorted(maps.Keys(attributes)) {
+		pairs = append(pairs, key+"="+attributes[key])
+	}
+	return strings.Join(pairs, ", ")
+}
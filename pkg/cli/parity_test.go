@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunParity(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/app/dev/main.tf": `module "service" {
+  source = "../../../modules/service"
+}
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}`,
+		"envs/app/prod/main.tf": `module "service" {
+  source = "../../../modules/service"
+}
+module "specific" {
+  source = "../../../modules/specific"
+}
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.1.0"
+}`,
+		"envs/db/dev/main.tf":      `module "service" { source = "../../../modules/service" }`,
+		"envs/db/prod/main.tf":     `module "service" { source = "../../../modules/service" }`,
+		"envs/shared/main.tf":      `resource "null_resource" "this" {}`,
+		"modules/service/main.tf":  `resource "null_resource" "this" {}`,
+		"modules/specific/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "Text",
+			args: []string{},
+			expected: `service=app (dev, prod)
+  modules/specific: dev=missing, prod=present
+  terraform-aws-modules/vpc/aws: dev=5.0.0, prod=5.1.0
+1 of 2 groups differ
+`,
+		},
+		{
+			name: "JSON",
+			args: []string{"--format", "json"},
+			expected: `{"groups":[{"attributes":{"service":"app"},"roots":{"dev":"envs/app/dev","prod":"envs/app/prod"},"differences":[` +
+				`{"module":"modules/specific","values":{"dev":"missing","prod":"present"}},` +
+				`{"module":"terraform-aws-modules/vpc/aws","values":{"dev":"5.0.0","prod":"5.1.0"}}]},` +
+				`{"attributes":{"service":"db"},"roots":{"dev":"envs/db/dev","prod":"envs/db/prod"},"differences":[]}]}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := append([]string{os.Args[0], "parity",
+				"--root-module-dir", filepath.Join(dir, "envs"),
+				"--base-path", dir,
+				"--path-pattern", "envs/{service}/{env}",
+				"--across", "env",
+				"--log-level", "error",
+			}, tt.args...)
+
+			if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected output:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestRunParity_DuplicateMember(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/app/eu/prod/main.tf": `resource "null_resource" "this" {}`,
+		"envs/app/us/prod/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	// The region is not part of the pattern, so both roots are prod of the same group
+	var buf bytes.Buffer
+	err := NewApp(&buf).Run(context.Background(), []string{os.Args[0], "parity",
+		"--root-module-dir", filepath.Join(dir, "envs"),
+		"--base-path", dir,
+		"--path-pattern", `^envs/(?P<service>[^/]+)/[^/]+/(?P<env>[^/]+)$`,
+		"--across", "env",
+		"--log-level", "error",
+	})
+	expected := "root modules envs/app/eu/prod and envs/app/us/prod have the same attributes and env=prod"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/analyzer"
+	"github.com/hurack3034217/tf-mod-watcher/internal/filekind"
+	gitpkg "github.com/hurack3034217/tf-mod-watcher/internal/git"
+	"github.com/hurack3034217/tf-mod-watcher/internal/metadata"
+	"github.com/hurack3034217/tf-mod-watcher/internal/workspace"
+)
+
+// schemaVersion is the version of the json-v2 output, see schema/result-v2.schema.json
+const schemaVersion = 2
+
+// Statuses of root modules in the json-v2 output
+const (
+	statusUpdated   = "updated"   // The root module or one of its child modules changed
+	statusDependent = "dependent" // The root module reads the state of an updated root module
+	statusUnchanged = "unchanged"
+	statusDeleted   = "deleted" // The root module no longer exists
+)
+
+// resultV2 is the output of the json-v2 format
+type resultV2 struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Commits       *commitRange `json:"commits"`
+	Roots         []rootResult `json:"roots"`
+	Warnings      []warning    `json:"warnings"`
+}
+
+// commitRange holds the compared commits
+type commitRange struct {
+	Before commit `json:"before"`
+	After  commit `json:"after"`
+}
+
+// commit is a commit reference as given and the hash it resolves to
+type commit struct {
+	Ref  string `json:"ref"`
+	Hash string `json:"hash"`
+}
+
+// rootResult is a root module in the json-v2 output. Paths are relative to the base path.
+type rootResult struct {
+	Path              string            `json:"path"`
+	AbsolutePath      string            `json:"absolutePath"`
+	Status            string            `json:"status"`
+	ChangedFiles      []string          `json:"changedFiles"`
+	ChangeKinds       []string          `json:"changeKinds"`
+	TriggeringModules []string          `json:"triggeringModules"`
+	Workspaces        []string          `json:"workspaces,omitempty"`
+	Attributes        map[string]string `json:"attributes,omitempty"`
+	Chains            []string          `json:"chains,omitempty"`
+	Metadata          *metadata.Root    `json:"metadata,omitempty"`
+}
+
+// resolveCommitRange resolves the compared commit references to their hashes
+func resolveCommitRange(gitRepoRootPath, beforeCommit, afterCommit string) (*commitRange, error) {
+	before, err := gitpkg.GetCommitForRef(gitRepoRootPath, beforeCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve before commit %s: %w", beforeCommit, err)
+	}
+	after, err := gitpkg.GetCommitForRef(gitRepoRootPath, afterCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve after commit %s: %w", afterCommit, err)
+	}
+
+	return &commitRange{
+		Before: commit{Ref: beforeCommit, Hash: before.Hash.String()},
+		After:  commit{Ref: afterCommit, Hash: after.Hash.String()},
+	}, nil
+}
+
+// buildResultV2 builds the json-v2 output listing every discovered and deleted root module
+func buildResultV2(a *analysis, options outputOptions, logger *slog.Logger) (*resultV2, error) {
+	changeAnalyzer, err := analyzer.NewAnalyzer(a.rootChangedFiles, a.basePath, logger)
+	if err != nil {
+		return nil, err
+	}
+	relativePaths := func(paths []string) ([]string, error) {
+		relPaths, err := analyzer.ConvertToRelativePaths(options.basePath, paths, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert paths: %w", err)
+		}
+		return relPaths, nil
+	}
+
+	roots := make([]rootResult, 0, len(a.rootModuleDirs))
+	seen := make(map[string]struct{})
+	for _, rootModuleDir := range a.rootModuleDirs {
+		absRootModuleDir, err := filepath.Abs(rootModuleDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for %s: %w", rootModuleDir, err)
+		}
+		if _, exists := seen[absRootModuleDir]; exists {
+			continue
+		}
+		seen[absRootModuleDir] = struct{}{}
+
+		workspaces := make([]string, 0)
+		for _, target := range a.targets {
+			if target.Root == absRootModuleDir && target.Workspace != "" {
+				workspaces = append(workspaces, target.Workspace)
+			}
+		}
+
+		status := statusUnchanged
+		switch {
+		case slices.Contains(a.updatedModuleDirs, absRootModuleDir), len(a.changedWorkspaces[absRootModuleDir]) > 0:
+			status = statusUpdated
+		case slices.Contains(a.dependentModuleDirs, absRootModuleDir):
+			status = statusDependent
+		}
+
+		changedFiles := make([]string, 0)
+		triggeringModules := make([]string, 0)
+		if status == statusUpdated {
+			changes, err := changeAnalyzer.CollectChanges(absRootModuleDir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to collect changes of %s: %w", absRootModuleDir, err)
+			}
+			changedFiles = append(changedFiles, changes.ChangedFiles...)
+			changedFiles = append(changedFiles, workspaceFiles(absRootModuleDir, a.changedFiles, a.conventions)...)
+			slices.Sort(changedFiles)
+			triggeringModules = changes.TriggeringModules
+		}
+
+		root := rootResult{
+			AbsolutePath: absRootModuleDir,
+			Status:       status,
+			ChangeKinds:  filekind.Of(changedFiles),
+			Workspaces:   workspaces,
+			Attributes:   deriveAttributes(options.basePath, absRootModuleDir, options.patterns),
+			Chains:       options.chains[absRootModuleDir],
+		}
+		relPaths, err := relativePaths([]string{absRootModuleDir})
+		if err != nil {
+			return nil, err
+		}
+		root.Path = relPaths[0]
+		if root.ChangedFiles, err = relativePaths(changedFiles); err != nil {
+			return nil, err
+		}
+		if root.TriggeringModules, err = relativePaths(triggeringModules); err != nil {
+			return nil, err
+		}
+		if options.includeMetadata {
+			root.Metadata, err = metadata.Collect(absRootModuleDir, options.basePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to collect metadata of %s: %w", absRootModuleDir, err)
+			}
+		}
+		roots = append(roots, root)
+	}
+
+	deletedModuleDirs, err := findDeletedRootModules(a.searchDirs, a.changedFiles)
+	if err != nil {
+		return nil, err
+	}
+	for _, deletedModuleDir := range deletedModuleDirs {
+		if !a.matchesFilters(deletedModuleDir) {
+			continue
+		}
+		changedFiles := make([]string, 0)
+		for changedFile := range a.changedFiles {
+			if filepath.Dir(changedFile) == deletedModuleDir {
+				changedFiles = append(changedFiles, changedFile)
+			}
+		}
+		slices.Sort(changedFiles)
+
+		root := rootResult{
+			AbsolutePath:      deletedModuleDir,
+			Status:            statusDeleted,
+			ChangeKinds:       filekind.Of(changedFiles),
+			TriggeringModules: []string{},
+			Attributes:        deriveAttributes(options.basePath, deletedModuleDir, options.patterns),
+		}
+		relPaths, err := relativePaths([]string{deletedModuleDir})
+		if err != nil {
+			return nil, err
+		}
+		root.Path = relPaths[0]
+		if root.ChangedFiles, err = relativePaths(changedFiles); err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+
+	slices.SortFunc(roots, func(a, b rootResult) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+
+	return &resultV2{
+		SchemaVersion: schemaVersion,
+		Commits:       a.commits,
+		Roots:         roots,
+		Warnings:      a.warnings.Warnings(),
+	}, nil
+}
+
+// workspaceFiles returns the changed files that follow a workspace convention of the root module
+func workspaceFiles(rootModuleDir string, changedFiles map[string]struct{}, conventions []workspace.Convention) []string {
+	files := make([]string, 0)
+	for changedFile := range changedFiles {
+		for _, convention := range conventions {
+			if _, ok := convention.Match(rootModuleDir, changedFile); ok {
+				files = append(files, changedFile)
+				break
+			}
+		}
+	}
+	return files
+}
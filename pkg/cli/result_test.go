@@ -0,0 +1,298 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+// validateSchema checks value against the subset of JSON Schema used by the published schemas
+func validateSchema(root, schema map[string]any, value any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		return validateSchema(root, root["$defs"].(map[string]any)[name].(map[string]any), value, path)
+	}
+
+	errs := make([]string, 0)
+	if schemaType, ok := schema["type"]; ok {
+		types := make([]string, 0)
+		switch schemaType := schemaType.(type) {
+		case string:
+			types = append(types, schemaType)
+		case []any:
+			for _, t := range schemaType {
+				types = append(types, t.(string))
+			}
+		}
+		if !slices.ContainsFunc(types, func(t string) bool { return matchesType(t, value) }) {
+			return append(errs, fmt.Sprintf("%s: expected %v, got %T", path, types, value))
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		errs = append(errs, fmt.Sprintf("%s: %v is not one of %v", path, value, enum))
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		for _, required := range asSlice(schema["required"]) {
+			if _, exists := value[required.(string)]; !exists {
+				errs = append(errs, fmt.Sprintf("%s: missing required property %s", path, required))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, property := range value {
+			if propertySchema, ok := properties[name].(map[string]any); ok {
+				errs = append(errs, validateSchema(root, propertySchema, property, path+"."+name)...)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					errs = append(errs, fmt.Sprintf("%s: unexpected property %s", path, name))
+				}
+			case map[string]any:
+				errs = append(errs, validateSchema(root, additional, property, path+"."+name)...)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				errs = append(errs, validateSchema(root, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+// matchesType reports whether a decoded JSON value has the given JSON Schema type
+func matchesType(schemaType string, value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return schemaType == "null"
+	case bool:
+		return schemaType == "boolean"
+	case float64:
+		return schemaType == "number" || (schemaType == "integer" && value == math.Trunc(value))
+	case string:
+		return schemaType == "string"
+	case []any:
+		return schemaType == "array"
+	case map[string]any:
+		return schemaType == "object"
+	}
+	return false
+}
+
+// asSlice returns value as a slice, or nil if it is not one
+func asSlice(value any) []any {
+	slice, _ := value.([]any)
+	return slice
+}
+
+// checkSchema decodes output and fails the test if it does not conform to the published schema
+func checkSchema(t *testing.T, schemaFile string, output []byte) map[string]any {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "schema", schemaFile))
+	if err != nil {
+		t.Fatalf("Failed to read schema: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	var value map[string]any
+	if err := json.Unmarshal(output, &value); err != nil {
+		t.Fatalf("Failed to parse output %s: %v", output, err)
+	}
+	for _, e := range validateSchema(schema, schema, value, "$") {
+		t.Errorf("Output does not conform to schema: %s", e)
+	}
+	return value
+}
+
+func TestRunAnalysis_JSONV2(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/network/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"roots/app/main.tf": `module "service" {
+  source = "../../modules/service"
+}
+
+data "terraform_remote_state" "network" {
+  backend = "s3"
+  config = {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"roots/app/env/prod.tfvars":   `size = "large"`,
+		"roots/db/main.tf":            `resource "null_resource" "this" {}`,
+		"modules/service/main.tf":     `resource "null_resource" "this" {}`,
+		"modules/service/empty/.keep": ``,
+	})
+
+	var buf bytes.Buffer
+	err := NewApp(&buf).Run(context.Background(), []string{
+		os.Args[0],
+		"--root-module-dir", filepath.Join(dir, "roots"),
+		"--root-module-dir", filepath.Join(dir, "modules", "service", "empty"),
+		"--base-path", dir,
+		"--changed-file", filepath.Join(dir, "roots", "network", "main.tf"),
+		"--changed-file", filepath.Join(dir, "roots", "removed", "main.tf"),
+		"--changed-file", filepath.Join(dir, "roots", "app", "env", "prod.tfvars"),
+		"--output-format", "json-v2",
+		"--workspace-var-file", "env/{workspace}.tfvars",
+		"--include-dependents",
+		"--include-metadata",
+		"--log-level", "error",
+	})
+	if err != nil {
+		t.Fatalf("NewApp().Run() failed: %v", err)
+	}
+
+	output := checkSchema(t, "result-v2.schema.json", buf.Bytes())
+
+	var result resultV2
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if result.SchemaVersion != schemaVersion || output["commits"] != nil {
+		t.Errorf("Unexpected schema version or commits: %s", buf.String())
+	}
+
+	statuses := make(map[string]string)
+	for _, root := range result.Roots {
+		statuses[root.Path] = root.Status
+		if root.AbsolutePath != filepath.Join(dir, root.Path) {
+			t.Errorf("Expected absolute path of %s to be under %s, got %s", root.Path, dir, root.AbsolutePath)
+		}
+	}
+	expectedStatuses := map[string]string{
+		"roots/app":     statusUpdated,
+		"roots/db":      statusUnchanged,
+		"roots/network": statusUpdated,
+		"roots/removed": statusDeleted,
+	}
+	if fmt.Sprint(statuses) != fmt.Sprint(expectedStatuses) {
+		t.Errorf("Expected statuses %v, got %v", expectedStatuses, statuses)
+	}
+
+	app := result.Roots[0]
+	if !slices.Equal(app.ChangedFiles, []string{"roots/app/env/prod.tfvars"}) || !slices.Equal(app.Workspaces, []string{"prod"}) {
+		t.Errorf("Unexpected app root: %+v", app)
+	}
+	if app.Metadata == nil || app.Metadata.Backend == nil {
+		t.Errorf("Expected metadata of app root, got %+v", app.Metadata)
+	}
+
+	if len(result.Warnings) != 1 || result.Warnings[0].Message != "No root modules found in directory" {
+		t.Errorf("Expected a warning about the empty directory, got %+v", result.Warnings)
+	}
+}
+
+func TestRunAnalysis_JSONV2Git(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("Failed to init repo: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	commitAll := func(message string) string {
+		t.Helper()
+		if err := worktree.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+			t.Fatalf("Failed to add files: %v", err)
+		}
+		hash, err := worktree.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+		return hash.String()
+	}
+
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/app/main.tf":       `module "service" { source = "../../modules/service" }`,
+		"roots/legacy/main.tf":    `resource "null_resource" "this" {}`,
+		"modules/service/main.tf": `resource "null_resource" "this" {}`,
+	})
+	before := commitAll("Initial commit")
+
+	testutil.WriteFiles(t, dir, map[string]string{
+		"modules/service/main.tf": `resource "null_resource" "that" {}`,
+	})
+	if err := os.RemoveAll(filepath.Join(dir, "roots", "legacy")); err != nil {
+		t.Fatalf("Failed to remove root module: %v", err)
+	}
+	after := commitAll("Update service and remove legacy")
+
+	var buf bytes.Buffer
+	err = NewApp(&buf).Run(context.Background(), []string{
+		os.Args[0],
+		"--root-module-dir", filepath.Join(dir, "roots"),
+		"--git-repository-root-path", dir,
+		"--output-format", "json-v2",
+		"--log-level", "error",
+	})
+	if err != nil {
+		t.Fatalf("NewApp().Run() failed: %v", err)
+	}
+
+	checkSchema(t, "result-v2.schema.json", buf.Bytes())
+
+	var result resultV2
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	expectedCommits := &commitRange{
+		Before: commit{Ref: "HEAD^", Hash: before},
+		After:  commit{Ref: "HEAD", Hash: after},
+	}
+	if result.Commits == nil || *result.Commits != *expectedCommits {
+		t.Errorf("Expected commits %+v, got %+v", expectedCommits, result.Commits)
+	}
+
+	expectedRoots := []rootResult{
+		{
+			Path:              "roots/app",
+			AbsolutePath:      filepath.Join(dir, "roots", "app"),
+			Status:            statusUpdated,
+			ChangedFiles:      []string{"modules/service/main.tf"},
+			ChangeKinds:       []string{"config"},
+			TriggeringModules: []string{"modules/service"},
+		},
+		{
+			Path:              "roots/legacy",
+			AbsolutePath:      filepath.Join(dir, "roots", "legacy"),
+			Status:            statusDeleted,
+			ChangedFiles:      []string{"roots/legacy/main.tf"},
+			ChangeKinds:       []string{"config"},
+			TriggeringModules: []string{},
+		},
+	}
+	if fmt.Sprintf("%+v", result.Roots) != fmt.Sprintf("%+v", expectedRoots) {
+		t.Errorf("Expected roots %+v, got %+v", expectedRoots, result.Roots)
+	}
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+	"github.com/hurack3034217/tf-mod-watcher/internal/sbom"
+)
+
+// newSBOMCommand creates the sbom command
+func newSBOMCommand(writer io.Writer) *cli.Command {
+	return &cli.Command{
+		Name:  "sbom",
+		Usage: "Exports the modules and providers used by the root modules as a software bill of materials",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Document format (" + strings.Join(sbom.Formats(), ", ") + ")",
+				Value: sbom.FormatCycloneDX,
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Usage: "Path to write the document to (default: standard output)",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return runSBOM(ctx, cmd, writer)
+		},
+	}
+}
+
+// runSBOM builds the module graph of all discovered root modules and renders their bill of materials
+func runSBOM(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	format := cmd.String("format")
+	if !slices.Contains(sbom.Formats(), format) {
+		return fmt.Errorf("unsupported SBOM format: %s", format)
+	}
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+	absBasePath, err := filepath.Abs(basePath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for %s: %w", basePath, err)
+	}
+	rootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Building module graph", "rootModules", len(rootModuleDirs))
+	graph, err := modulegraph.Build(rootModuleDirs, basePath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to build module graph: %w", err)
+	}
+
+	bom, err := sbom.Collect(graph, filepath.Base(absBasePath))
+	if err != nil {
+		return fmt.Errorf("failed to collect SBOM: %w", err)
+	}
+	bom.Created = time.Now().UTC().Truncate(time.Second)
+
+	data, err := bom.Render(format)
+	if err != nil {
+		return err
+	}
+	return writeOutput(cmd.String("output"), data, writer)
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunSBOM(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+module "network" {
+  source = "../../modules/network"
+}`,
+		"envs/prod/.terraform.lock.hcl": `provider "registry.terraform.io/hashicorp/aws" {
+  version = "5.31.0"
+  hashes = [
+    "zh:0123abcd",
+  ]
+}`,
+		"modules/network/main.tf": `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}`,
+	})
+
+	tests := []struct {
+		name          string
+		format        string
+		expectedKey   string
+		expectedValue string
+		expectedError bool
+	}{
+		{
+			name:          "CycloneDX",
+			format:        "cyclonedx",
+			expectedKey:   "bomFormat",
+			expectedValue: "CycloneDX",
+		},
+		{
+			name:          "SPDX",
+			format:        "spdx",
+			expectedKey:   "spdxVersion",
+			expectedValue: "SPDX-2.3",
+		},
+		{
+			name:          "Unsupported format",
+			format:        "syft",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "sbom.json")
+			var buf bytes.Buffer
+			args := []string{os.Args[0], "sbom", "--root-module-dir", filepath.Join(dir, "envs"), "--base-path", dir, "--format", tt.format, "--output", output, "--log-level", "error"}
+
+			err := NewApp(&buf).Run(context.Background(), args)
+			if tt.expectedError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("Failed to read output: %v", err)
+			}
+			var document map[string]any
+			if err := json.Unmarshal(data, &document); err != nil {
+				t.Fatalf("Failed to parse output: %v", err)
+			}
+			if document[tt.expectedKey] != tt.expectedValue {
+				t.Errorf("Expected %s %q, got %v", tt.expectedKey, tt.expectedValue, document[tt.expectedKey])
+			}
+			for _, expected := range []string{"envs/prod", "modules/network", "terraform-aws-modules/vpc/aws", "registry.terraform.io/hashicorp/aws", "5.31.0", "0123abcd"} {
+				if !bytes.Contains(data, []byte(`"`+expected+`"`)) {
+					t.Errorf("Expected %s in the document", expected)
+				}
+			}
+		})
+	}
+}
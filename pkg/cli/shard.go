@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/analyzer"
+	"github.com/hurack3034217/tf-mod-watcher/internal/shard"
+	"github.com/hurack3034217/tf-mod-watcher/internal/terraform"
+	"github.com/hurack3034217/tf-mod-watcher/internal/workspace"
+)
+
+// Cost metrics of a root module used to balance shards
+const (
+	shardWeightRoots     = "roots"     // Every root module weighs the same
+	shardWeightModules   = "modules"   // Number of local modules in the closure of the root module
+	shardWeightResources = "resources" // Number of resource blocks in the closure of the root module
+)
+
+// shardWeights lists the supported values of the shard-weight flag
+var shardWeights = []string{shardWeightRoots, shardWeightModules, shardWeightResources}
+
+// shardResult is a shard in the shards output
+type shardResult struct {
+	Index  int   `json:"index"`
+	Weight int   `json:"weight"`
+	Roots  []any `json:"roots"`
+}
+
+// shardFlags returns the flags for splitting the targets across CI runners
+func shardFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:  "shard-count",
+			Usage: "Number of shards to split the updated root modules into",
+		},
+		&cli.IntFlag{
+			Name:  "shard-index",
+			Usage: "Only output the root modules of the shard with this zero-based index",
+		},
+		&cli.StringFlag{
+			Name:  "shard-weight",
+			Value: shardWeightRoots,
+			Usage: "Cost metric balancing the shards (roots, modules, resources)",
+		},
+	}
+}
+
+// partitionTargets splits the roots of the targets into shards. A root weighs its cost times its number of targets,
+// since every workspace of a root module runs in the same shard.
+func partitionTargets(a *analysis, count int, metric string, logger *slog.Logger) ([]shard.Shard, error) {
+	roots := make([]string, 0)
+	targetCounts := make(map[string]int)
+	for _, target := range a.targets {
+		if !slices.Contains(roots, target.Root) {
+			roots = append(roots, target.Root)
+		}
+		targetCounts[target.Root]++
+	}
+
+	weights := make(map[string]int, len(roots))
+	for _, root := range roots {
+		cost, err := rootCost(root, metric, logger)
+		if err != nil {
+			return nil, err
+		}
+		weights[root] = cost * targetCounts[root]
+	}
+
+	shards, err := shard.Partition(roots, weights, a.graph, count)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range shards {
+		logger.Debug("Partitioned shard", "index", s.Index, "weight", s.Weight, "roots", len(s.Roots))
+	}
+	return shards, nil
+}
+
+// rootCost returns the cost of the root module for the given metric
+func rootCost(root, metric string, logger *slog.Logger) (int, error) {
+	switch metric {
+	case shardWeightModules, shardWeightResources:
+		closure, err := analyzer.FindModuleClosure(root, logger)
+		if err != nil {
+			return 0, err
+		}
+		if metric == shardWeightModules {
+			return len(closure), nil
+		}
+
+		// A root module without resources still costs a run
+		cost := 1
+		for _, dir := range closure {
+			count, err := terraform.CountResources(dir)
+			if err != nil {
+				return 0, fmt.Errorf("failed to count resources of %s: %w", dir, err)
+			}
+			cost += count
+		}
+		return cost, nil
+	default:
+		return 1, nil
+	}
+}
+
+// shardTargets returns the targets whose root module is in the shard
+func shardTargets(targets []workspace.Target, s shard.Shard) []workspace.Target {
+	filtered := make([]workspace.Target, 0)
+	for _, target := range targets {
+		if slices.Contains(s.Roots, target.Root) {
+			filtered = append(filtered, target)
+		}
+	}
+	return filtered
+}
+
+// buildShards builds the shards output with the entries of every shard
+func buildShards(shards []shard.Shard, targets []workspace.Target, options outputOptions, logger *slog.Logger) ([]shardResult, error) {
+	results := make([]shardResult, 0, len(shards))
+	for _, s := range shards {
+		entries, err := buildEntries(shardTargets(targets, s), options, logger)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, shardResult{Index: s.Index, Weight: s.Weight, Roots: entries})
+	}
+	return results, nil
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunAnalysis_Shards(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/network/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}
+module "service" {
+  source = "../../modules/service"
+}`,
+		"roots/app/main.tf": `module "service" {
+  source = "../../modules/service"
+}
+data "terraform_remote_state" "network" {
+  backend = "s3"
+  config = {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"roots/db/main.tf": `module "service" {
+  source = "../../modules/service"
+}
+resource "null_resource" "a" {}
+resource "null_resource" "b" {}
+resource "null_resource" "c" {}
+resource "null_resource" "d" {}`,
+		"roots/batch/main.tf": `module "service" {
+  source = "../../modules/service"
+}`,
+		"modules/service/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	tests := []struct {
+		name      string
+		args      []string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "Shards",
+			args:     []string{"--output-format", "shards", "--shard-count", "2"},
+			expected: `[{"index":0,"weight":2,"roots":["roots/app","roots/network"]},{"index":1,"weight":2,"roots":["roots/batch","roots/db"]}]`,
+		},
+		{
+			name:     "Shards weighted by resources",
+			args:     []string{"--output-format", "shards", "--shard-count", "2", "--shard-weight", "resources"},
+			expected: `[{"index":0,"weight":6,"roots":["roots/db"]},{"index":1,"weight":6,"roots":["roots/app","roots/batch","roots/network"]}]`,
+		},
+		{
+			name:     "Shard index",
+			args:     []string{"--shard-count", "2", "--shard-index", "1"},
+			expected: `["roots/batch","roots/db"]`,
+		},
+		{
+			name:     "Shard index with waves",
+			args:     []string{"--output-format", "waves", "--shard-count", "2", "--shard-index", "0"},
+			expected: `[["roots/network"],["roots/app"]]`,
+		},
+		{
+			name:      "Shards without shard count",
+			args:      []string{"--output-format", "shards"},
+			expectErr: true,
+		},
+		{
+			name:      "Shard index out of range",
+			args:      []string{"--shard-count", "2", "--shard-index", "2"},
+			expectErr: true,
+		},
+		{
+			name:      "Shard index with json-v2",
+			args:      []string{"--output-format", "json-v2", "--shard-count", "2", "--shard-index", "0"},
+			expectErr: true,
+		},
+		{
+			name:      "Unsupported shard weight",
+			args:      []string{"--shard-count", "2", "--shard-weight", "lines"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewApp(&buf).Run(context.Background(), append([]string{
+				os.Args[0],
+				"--root-module-dir", filepath.Join(dir, "roots"),
+				"--base-path", dir,
+				"--changed-file", filepath.Join(dir, "modules", "service", "main.tf"),
+				"--log-level", "error",
+			}, tt.args...))
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewApp().Run() failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected output %s, got %s", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/stack"
+	"github.com/hurack3034217/tf-mod-watcher/internal/workspace"
+)
+
+// buildStackGraph builds the dependency graph between root modules from remote state
+// data sources and explicit stack dependencies
+func buildStackGraph(rootModuleDirs []string, stackDependencies []string, logger *slog.Logger) (*stack.Graph, error) {
+	graph, err := stack.Build(rootModuleDirs, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dependency := range stackDependencies {
+		root, dependsOn, err := parseStackDependency(dependency)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debug("Adding explicit stack dependency", "root", root, "dependsOn", dependsOn)
+		if err := graph.AddDependency(root, dependsOn); err != nil {
+			return nil, fmt.Errorf("invalid stack dependency %s: %w", dependency, err)
+		}
+	}
+
+	return graph, nil
+}
+
+// parseStackDependency parses a stack dependency in the form <root>=<depends-on>
+// and returns both directories as absolute paths
+func parseStackDependency(dependency string) (string, string, error) {
+	root, dependsOn, found := strings.Cut(dependency, "=")
+	if !found || root == "" || dependsOn == "" {
+		return "", "", fmt.Errorf("invalid stack dependency %s: expected <root>=<depends-on>", dependency)
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get absolute path for %s: %w", root, err)
+	}
+	absDependsOn, err := filepath.Abs(dependsOn)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get absolute path for %s: %w", dependsOn, err)
+	}
+
+	return absRoot, absDependsOn, nil
+}
+
+// buildWaves orders the targets into waves of output entries.
+// All workspaces of a root module are in the same wave.
+func buildWaves(graph *stack.Graph, targets []workspace.Target, options outputOptions, logger *slog.Logger) ([][]any, error) {
+	roots := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if !slices.Contains(roots, target.Root) {
+			roots = append(roots, target.Root)
+		}
+	}
+
+	waves, err := graph.Waves(roots)
+	if err != nil {
+		return nil, fmt.Errorf("failed to order root modules: %w", err)
+	}
+
+	entryWaves := make([][]any, 0, len(waves))
+	for _, wave := range waves {
+		waveTargets := make([]workspace.Target, 0, len(wave))
+		for _, target := range targets {
+			if slices.Contains(wave, target.Root) {
+				waveTargets = append(waveTargets, target)
+			}
+		}
+
+		entries, err := buildEntries(waveTargets, options, logger)
+		if err != nil {
+			return nil, err
+		}
+		entryWaves = append(entryWaves, entries)
+	}
+
+	return entryWaves, nil
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestParseStackDependency(t *testing.T) {
+	tests := []struct {
+		name        string
+		dependency  string
+		shouldError bool
+	}{
+		{
+			name:       "Valid dependency",
+			dependency: "environments/service=environments/network",
+		},
+		{
+			name:        "Missing separator",
+			dependency:  "environments/service",
+			shouldError: true,
+		},
+		{
+			name:        "Missing dependency",
+			dependency:  "environments/service=",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, dependsOn, err := parseStackDependency(tt.dependency)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !filepath.IsAbs(root) || !filepath.IsAbs(dependsOn) {
+				t.Errorf("Expected absolute paths, got %s and %s", root, dependsOn)
+			}
+		})
+	}
+}
+
+func TestRunAnalysis_Waves(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/network/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"roots/service/main.tf": `data "terraform_remote_state" "network" {
+  backend = "s3"
+  config = {
+    bucket = "tfstate"
+    key    = "network.tfstate"
+  }
+}`,
+		"roots/batch/main.tf":  `resource "null_resource" "this" {}`,
+		"roots/worker/main.tf": `resource "null_resource" "this" {}`,
+	})
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "Waves of updated roots only",
+			args: []string{
+				"--changed-file", filepath.Join(dir, "roots", "network", "main.tf"),
+				"--output-format", "waves",
+			},
+			expected: `[["roots/network"]]`,
+		},
+		{
+			name: "Waves including dependents",
+			args: []string{
+				"--changed-file", filepath.Join(dir, "roots", "network", "main.tf"),
+				"--output-format", "waves",
+				"--include-dependents",
+			},
+			expected: `[["roots/network"],["roots/service"]]`,
+		},
+		{
+			name: "Explicit stack dependency",
+			args: []string{
+				"--changed-file", filepath.Join(dir, "roots", "batch", "main.tf"),
+				"--changed-file", filepath.Join(dir, "roots", "worker", "main.tf"),
+				"--stack-dependency", filepath.Join(dir, "roots", "worker") + "=" + filepath.Join(dir, "roots", "batch"),
+				"--output-format", "waves",
+			},
+			expected: `[["roots/batch"],["roots/worker"]]`,
+		},
+		{
+			name: "Flat output including dependents",
+			args: []string{
+				"--changed-file", filepath.Join(dir, "roots", "network", "main.tf"),
+				"--include-dependents",
+			},
+			expected: `["roots/network","roots/service"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := append([]string{os.Args[0], "--root-module-dir", filepath.Join(dir, "roots"), "--base-path", dir, "--log-level", "error"}, tt.args...)
+			if err := NewApp(&buf).Run(context.Background(), args); err != nil {
+				t.Fatalf("NewApp().Run() failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected output %s, got %s", tt.expected, buf.String())
+			}
+		})
+	}
+}
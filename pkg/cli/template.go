@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs returns the functions available in user-supplied templates
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// slug converts a path and an optional workspace to a name usable in job names
+		"slug": func(path string, workspace ...string) string {
+			return slug(path, strings.Join(workspace, "-"))
+		},
+		// join joins elements with a separator, e.g. {{ .DependsOn | join "," }}
+		"join": func(sep string, elems []string) string {
+			return strings.Join(elems, sep)
+		},
+		// relpath returns target relative to base with forward slashes, e.g. {{ .Path | relpath "terraform" }}
+		"relpath": func(base, target string) (string, error) {
+			relPath, err := filepath.Rel(base, target)
+			if err != nil {
+				return "", err
+			}
+			return filepath.ToSlash(relPath), nil
+		},
+		// toJSON renders a value as JSON, which is also valid YAML
+		"toJSON": func(value any) (string, error) {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		},
+	}
+}
+
+// parseTemplateFile parses a user-supplied template file with the template functions
+func parseTemplateFile(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+	return parseTemplate(path, string(data))
+}
+
+// parseOutputTemplate parses the output-template flag value, which is either an inline
+// template or the path of a template file prefixed with @
+func parseOutputTemplate(value string) (*template.Template, error) {
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		return parseTemplateFile(path)
+	}
+	return parseTemplate("output-template", value)
+}
+
+// parseTemplate parses a user-supplied template with the template functions
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestParseTemplateFile(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"valid.tmpl":   `{{ slug .Path .Workspace }} {{ .Needs | join "," }} {{ toJSON .Needs }} {{ .Path | relpath "roots" }}`,
+		"invalid.tmpl": `{{ .Path `,
+	})
+
+	tmpl, err := parseTemplateFile(filepath.Join(dir, "valid.tmpl"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	data := map[string]any{"Path": "roots/App", "Workspace": "prod", "Needs": []string{"a", "b"}}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `roots-app-prod a,b ["a","b"] App`
+	if buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+
+	if _, err := parseTemplateFile(filepath.Join(dir, "invalid.tmpl")); err == nil {
+		t.Error("Expected error for invalid template")
+	}
+	if _, err := parseTemplateFile(filepath.Join(dir, "missing.tmpl")); err == nil {
+		t.Error("Expected error for missing template")
+	}
+}
+
+func TestRunAnalysis_OutputTemplate(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"roots/app/main.tf": `module "service" {
+  source = "../../modules/service"
+}`,
+		"roots/db/main.tf": `terraform {
+  backend "s3" {
+    bucket = "tfstate"
+    key    = "db.tfstate"
+  }
+}`,
+		"roots/network/main.tf":   `resource "null_resource" "this" {}`,
+		"modules/service/main.tf": `resource "null_resource" "this" {}`,
+		"roots.tmpl": `{{ range .Roots }}{{ if ne .Status "unchanged" }}{{ .Path | relpath "roots" }},{{ .Status }},{{ .Metadata.Backend.Type }},{{ .TriggeringModules | join ";" }}
+{{ end }}{{ end }}`,
+	})
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "Inline",
+			template: `{{ range .Roots }}{{ if eq .Status "updated" }}{{ .Path }} {{ end }}{{ end }}`,
+			expected: "roots/app roots/db ",
+		},
+		{
+			name:     "File",
+			template: "@" + filepath.Join(dir, "roots.tmpl"),
+			expected: "app,updated,local,modules/service\ndb,updated,s3,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewApp(&buf).Run(context.Background(), []string{
+				os.Args[0],
+				"--root-module-dir", filepath.Join(dir, "roots"),
+				"--base-path", dir,
+				"--changed-file", filepath.Join(dir, "modules", "service", "main.tf"),
+				"--changed-file", filepath.Join(dir, "roots", "db", "main.tf"),
+				"--output-template", tt.template,
+				"--log-level", "error",
+			})
+			if err != nil {
+				t.Fatalf("NewApp().Run() failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected output %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+
+	err := NewApp(&bytes.Buffer{}).Run(context.Background(), []string{
+		os.Args[0],
+		"--root-module-dir", filepath.Join(dir, "roots"),
+		"--base-path", dir,
+		"--changed-file", filepath.Join(dir, "roots", "db", "main.tf"),
+		"--output-template", "{{ .Roots }}",
+		"--output-format", "json-v2",
+		"--log-level", "error",
+	})
+	if err == nil {
+		t.Error("Expected error when output-template is combined with output-format")
+	}
+}
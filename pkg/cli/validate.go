@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/modulegraph"
+	"github.com/hurack3034217/tf-mod-watcher/internal/validate"
+)
+
+// newValidateCommand creates the validate command
+func newValidateCommand(writer io.Writer) *cli.Command {
+	return &cli.Command{
+		Name:  "validate",
+		Usage: "Reports module sources that cannot be followed",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return runValidate(ctx, cmd, writer)
+		},
+	}
+}
+
+// runValidate checks the sources of the module blocks of the root modules and the local modules they use
+func runValidate(ctx context.Context, cmd *cli.Command, writer io.Writer) error {
+	logger := setupLogger(cmd)
+
+	basePath, err := resolveBasePath(cmd.String("base-path"), logger)
+	if err != nil {
+		return err
+	}
+	rootModuleDirs, err := discoverRootModules(cmd.StringSlice("root-module-dir"), logger)
+	if err != nil {
+		return err
+	}
+
+	// Broken sources are reported below instead of being logged while building the graph
+	graph, err := modulegraph.Build(rootModuleDirs, basePath, slog.New(slog.DiscardHandler))
+	if err != nil {
+		return fmt.Errorf("failed to build module graph: %w", err)
+	}
+	problems, err := validateModules(graph, repositoryRoot(basePath, logger), logger)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		_, err = fmt.Fprintln(writer, "No invalid module sources found")
+		if err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	var report strings.Builder
+	for _, problem := range problems {
+		fmt.Fprintf(&report, "%s:%d: module %q: %s\n", relativeFile(basePath, problem.Call.File), problem.Call.Line, problem.Call.Name, problem.Message)
+	}
+
+	_, err = io.WriteString(writer, report.String())
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return fmt.Errorf("found %d invalid module sources", len(problems))
+}
+
+// validateModules checks the module blocks of every local module in the graph
+func validateModules(graph *modulegraph.Graph, repoRoot string, logger *slog.Logger) ([]validate.Problem, error) {
+	moduleDirs := make([]string, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		if node.Dir != "" {
+			moduleDirs = append(moduleDirs, node.Dir)
+		}
+	}
+
+	problems, err := validate.Modules(moduleDirs, repoRoot, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate modules: %w", err)
+	}
+	return problems, nil
+}
+
+// warnInvalidSources logs a warning for every module source that cannot be followed,
+// since changes behind it are not detected
+func warnInvalidSources(graph *modulegraph.Graph, repoRoot, basePath string, logger *slog.Logger) error {
+	problems, err := validateModules(graph, repoRoot, logger)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		logger.Warn("Invalid module source", "file", relativeFile(basePath, problem.Call.File), "line", problem.Call.Line,
+			"module", problem.Call.Name, "kind", problem.Kind, "problem", problem.Message)
+	}
+	return nil
+}
+
+// relativeFile returns the path of the file relative to the base path, or the path as is if that fails
+func relativeFile(basePath, file string) string {
+	absBasePath, err := filepath.Abs(basePath)
+	if err != nil {
+		return file
+	}
+	relPath, err := filepath.Rel(absBasePath, file)
+	if err != nil {
+		return file
+	}
+	return filepath.ToSlash(relPath)
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurack3034217/tf-mod-watcher/internal/testutil"
+)
+
+func TestRunValidate(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{
+		"envs/prod/main.tf": `module "service" {
+  source = "../../modules/service"
+}
+module "typo" {
+  source = "../../modules/servce/service-1"
+}`,
+		"envs/dev/main.tf": `module "service" {
+  source = "../../modules/service"
+}`,
+		"modules/service/main.tf": `module "dynamic" {
+  source = "./${var.name}"
+}`,
+		"modules/network/main.tf": `resource "null_resource" "this" {}`,
+		"stacks/network/main.tf": `module "network" {
+  source = "../../modules/network"
+}`,
+	})
+
+	tests := []struct {
+		name           string
+		rootModuleDir  string
+		expectedOutput string
+		expectedError  bool
+	}{
+		{
+			name:          "Invalid sources found",
+			rootModuleDir: filepath.Join(dir, "envs"),
+			expectedOutput: `envs/prod/main.tf:5: module "typo": source ../../modules/servce/service-1 does not exist
+modules/service/main.tf:2: module "dynamic": source is not a literal string
+`,
+			expectedError: true,
+		},
+		{
+			name:           "No invalid sources",
+			rootModuleDir:  filepath.Join(dir, "stacks"),
+			expectedOutput: "No invalid module sources found\n",
+			expectedError:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := []string{os.Args[0], "validate", "--root-module-dir", tt.rootModuleDir, "--base-path", dir, "--log-level", "error"}
+
+			err := NewApp(&buf).Run(context.Background(), args)
+			if tt.expectedError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectedError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, buf.String())
+			}
+		})
+	}
+}
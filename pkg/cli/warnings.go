@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// warning is a warning logged during the analysis
+type warning struct {
+	Message    string            `json:"message"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// warningRecorder is a slog.Handler that records warnings before passing records on to the wrapped handler.
+// Warnings are recorded even if the wrapped handler discards them because of the log level.
+type warningRecorder struct {
+	handler  slog.Handler
+	attrs    []slog.Attr
+	mu       *sync.Mutex
+	warnings *[]warning
+}
+
+// newWarningRecorder creates a warningRecorder wrapping the given handler
+func newWarningRecorder(handler slog.Handler) *warningRecorder {
+	return &warningRecorder{
+		handler:  handler,
+		mu:       &sync.Mutex{},
+		warnings: &[]warning{},
+	}
+}
+
+// Enabled implements slog.Handler
+func (r *warningRecorder) Enabled(ctx context.Context, level slog.Level) bool {
+	return level == slog.LevelWarn || r.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (r *warningRecorder) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level == slog.LevelWarn {
+		r.record(record)
+	}
+	if !r.handler.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return r.handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (r *warningRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &warningRecorder{
+		handler:  r.handler.WithAttrs(attrs),
+		attrs:    slices.Concat(r.attrs, attrs),
+		mu:       r.mu,
+		warnings: r.warnings,
+	}
+}
+
+// WithGroup implements slog.Handler
+func (r *warningRecorder) WithGroup(name string) slog.Handler {
+	return &warningRecorder{
+		handler:  r.handler.WithGroup(name),
+		attrs:    r.attrs,
+		mu:       r.mu,
+		warnings: r.warnings,
+	}
+}
+
+// Warnings returns the recorded warnings in the order they were logged
+func (r *warningRecorder) Warnings() []warning {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(*r.warnings)
+}
+
+// record adds a warning for the record unless the same warning was already recorded
+func (r *warningRecorder) record(record slog.Record) {
+	w := warning{Message: record.Message}
+	addAttr := func(attr slog.Attr) bool {
+		if w.Attributes == nil {
+			w.Attributes = make(map[string]string)
+		}
+		w.Attributes[attr.Key] = attr.Value.Resolve().String()
+		return true
+	}
+	for _, attr := range r.attrs {
+		addAttr(attr)
+	}
+	record.Attrs(addAttr)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	duplicate := slices.ContainsFunc(*r.warnings, func(recorded warning) bool {
+		return recorded.Message == w.Message && maps.Equal(recorded.Attributes, w.Attributes)
+	})
+	if !duplicate {
+		*r.warnings = append(*r.warnings, w)
+	}
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWarningRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := newWarningRecorder(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError}))
+	logger := slog.New(recorder)
+
+	logger.Info("Analyzing root modules")
+	logger.Warn("No root modules found in directory", "directory", "roots")
+	logger.Warn("No root modules found in directory", "directory", "roots")
+	logger.With("module", "modules/a").Warn("Failed to find child modules")
+	logger.Error("Multiple root modules write to the same state")
+
+	expected := []warning{
+		{Message: "No root modules found in directory", Attributes: map[string]string{"directory": "roots"}},
+		{Message: "Failed to find child modules", Attributes: map[string]string{"module": "modules/a"}},
+	}
+	if !reflect.DeepEqual(recorder.Warnings(), expected) {
+		t.Errorf("Expected warnings %+v, got %+v", expected, recorder.Warnings())
+	}
+
+	// Records below the level of the wrapped handler are not passed on
+	if strings.Contains(buf.String(), "level=WARN") || !strings.Contains(buf.String(), "level=ERROR") {
+		t.Errorf("Unexpected log output: %s", buf.String())
+	}
+}